@@ -4,4 +4,4 @@
 
 set -e # Exit on failure
 
-go build -o /tmp/build-shell-go app/*.go
+go build -o /tmp/build-shell-go ./cmd/gsh
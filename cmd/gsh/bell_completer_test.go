@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/KayaLuken/golang-shell/interp"
+	"github.com/chzyer/readline"
+)
+
+// DummyCompleter implements PrefixCompleterInterface for testing
+type DummyCompleter struct {
+	suggestions [][]rune
+	length      int
+}
+
+func (d *DummyCompleter) Do(line []rune, pos int) ([][]rune, int) {
+	return d.suggestions, d.length
+}
+
+func (d *DummyCompleter) GetChildren() []readline.PrefixCompleterInterface {
+	return nil
+}
+
+func (d *DummyCompleter) GetName() []rune {
+	return []rune{}
+}
+
+func (d *DummyCompleter) Print(prefix string, level int, buf *bytes.Buffer) {
+	// No-op for dummy
+}
+
+func (d *DummyCompleter) SetChildren(children []readline.PrefixCompleterInterface) {
+	// No-op for dummy
+}
+
+func TestBellCompleter_NoSuggestions_RingsBellAndResetsTabCount(t *testing.T) {
+	// Capture stdout
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	bc := &bellCompleter{
+		PrefixCompleterInterface: &DummyCompleter{suggestions: nil, length: 0},
+	}
+	bc.tabCount = 42 // set to nonzero to check reset
+	bc.lastLine = "old"
+	bc.Do([]rune("foo"), 3)
+
+	w.Close()
+	os.Stdout = oldStdout
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+
+	if !strings.Contains(buf.String(), "\a") {
+		t.Errorf("expected bell character to be printed")
+	}
+	if bc.tabCount != 0 {
+		t.Errorf("tabCount = %d, want 0", bc.tabCount)
+	}
+	if bc.lastLine != "foo" {
+		t.Errorf("lastLine = %q, want %q", bc.lastLine, "foo")
+	}
+}
+
+func TestCommonPrefix(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"foobar", "foobaz", "fooba"},
+		{"foo", "foo", "foo"},
+		{"foo", "bar", ""},
+		{"", "bar", ""},
+		{"foo", "", ""},
+		{"abc", "abcd", "abc"},
+	}
+	for _, tt := range tests {
+		got := commonPrefix(tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf("commonPrefix(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFormatListing(t *testing.T) {
+	names := []string{"a", "bb", "ccc", "dddd", "e"}
+	tests := []struct {
+		width int
+		descs []string
+		want  string
+	}{
+		{80, nil, "a     bb    ccc   dddd  e\n"},
+		{14, nil, "a     dddd\nbb    e\nccc\n"},
+		{3, nil, "a\nbb\nccc\ndddd\ne\n"},
+		{80, []string{"first", "", "", "fourth", ""}, "a     -- first\nbb\nccc\ndddd  -- fourth\ne\n"},
+	}
+	for _, tt := range tests {
+		if got := formatListing(names, tt.descs, tt.width); got != tt.want {
+			t.Errorf("formatListing(%d, %q) = %q, want %q", tt.width, tt.descs, got, tt.want)
+		}
+	}
+}
+
+func TestBellCompleter_Listing(t *testing.T) {
+	r := interp.New()
+	var words []string
+	for i := range 150 {
+		words = append(words, fmt.Sprintf("w%03d", i))
+	}
+	err := r.RunString(context.Background(), `
+complete -W "alpha beta" few
+complete -W "`+strings.Join(words, " ")+`" many
+_desc() { COMPREPLY=("build	Build it" "bump	Bump the version"); }
+complete -F _desc desc
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	b := &bellCompleter{runner: r, out: &out, width: 40, prompt: "> "}
+	tab := func(line string, pos int) string {
+		t.Helper()
+		out.Reset()
+		if got, _ := b.Do([]rune(line), pos); got != nil {
+			t.Fatalf("Do(%q) completed %q", line, got)
+		}
+		return out.String()
+	}
+
+	tab("few x", 4)
+	// The line is shown again in full, whatever the cursor position
+	if got, want := tab("few x", 4), "> few x\nalpha  beta\n"; got != want {
+		t.Errorf("listing = %q, want %q", got, want)
+	}
+
+	tab("desc bu", 7)
+	if got, want := tab("desc bu", 7), "> desc bu\nbuild  -- Build it\nbump   -- Bump the version\n"; got != want {
+		t.Errorf("listing with descriptions = %q, want %q", got, want)
+	}
+
+	tab("many w", 6)
+	if got, want := tab("many w", 6), "> many w\nDisplay all 150 possibilities? (y or n)\n"; got != want {
+		t.Errorf("question = %q, want %q", got, want)
+	}
+	out.Reset()
+	if _, ok := b.FilterInput('y'); ok {
+		t.Errorf("the answer to the question was passed on")
+	}
+	if got, want := out.String(), formatListing(words, nil, 40); got != want {
+		t.Errorf("listing after yes = %q, want %q", got, want)
+	}
+	if _, ok := b.FilterInput('y'); !ok {
+		t.Errorf("a key typed after the answer was not passed on")
+	}
+
+	tab("many w", 6)
+	out.Reset()
+	b.FilterInput('n')
+	if out.Len() != 0 {
+		t.Errorf("listed %q after no", out.String())
+	}
+}
+
+func TestBellCompleter_Menu(t *testing.T) {
+	r := interp.New()
+	r.SetOption("menucomplete", true)
+	if err := r.RunString(context.Background(), `complete -W "alpha 'a b' beta" m`); err != nil {
+		t.Fatal(err)
+	}
+	b := &bellCompleter{runner: r}
+
+	// Readline shows the completions as the word typed so far followed by
+	// what each inserts
+	got, offset := b.Do([]rune("m "), 2)
+	want := [][]rune{[]rune("a b "), []rune("alpha "), []rune("beta ")}
+	if !slices.EqualFunc(got, want, slices.Equal) || offset != 0 {
+		t.Errorf("Do = %q, %d, want %q, 0", got, offset, want)
+	}
+	if got, offset = b.Do([]rune("m a"), 3); len(got) != 2 || offset != 1 {
+		t.Errorf("Do = %q, %d, want 2 completions of 1 rune", got, offset)
+	}
+
+	esc := func() byte {
+		p := []byte{readline.CharEsc}
+		escReader{bytes.NewReader(p), b}.Read(p)
+		return p[0]
+	}
+	// Until readline opens the menu, which it does on the next Tab, an
+	// Esc may start an escape sequence such as an arrow key's
+	selecting := false
+	b.selecting = func() bool { return selecting }
+	b.FilterInput(readline.CharTab)
+	b.OnChange([]rune("m a"), 3, readline.CharTab)
+	if got := esc(); got != readline.CharEsc {
+		t.Errorf("Esc before the menu opened read as %q", got)
+	}
+	selecting = true
+	b.FilterInput(readline.CharTab)
+	b.OnChange([]rune("m a"), 3, readline.CharTab)
+	b.FilterInput(readline.CharNext)
+	if got := esc(); got != readline.CharBell {
+		t.Errorf("Esc in the menu read as %q, want Ctrl-G", got)
+	}
+	b.FilterInput(readline.CharBell)
+	if got := esc(); got != readline.CharEsc {
+		t.Errorf("Esc after the menu closed read as %q", got)
+	}
+
+	// Without the option, the shell lists completions itself
+	r.SetOption("menucomplete", false)
+	if got, _ := b.Do([]rune("m "), 2); got != nil {
+		t.Errorf("Do without menucomplete = %q, want nothing", got)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+// commandCache holds the names of the executables in the directories of
+// $PATH, so that completing a command name doesn't read every directory on
+// each tab. It is rebuilt when $PATH changes, and a directory is read again
+// when its modification time changes, as it does when a file is added to it,
+// removed or renamed.
+type commandCache struct {
+	dirs    []string // the directories of the $PATH the cache is for
+	entries map[string]*dirCommands
+	names   []string // the names in all the directories, sorted
+}
+
+// dirCommands are the executables found in a directory.
+type dirCommands struct {
+	modTime time.Time
+	names   []string
+}
+
+// pathDirs returns the directories named in $PATH. An empty one, or one not
+// starting with /, is relative to the working directory.
+func pathDirs(r *interp.Runner) []string {
+	path, _ := r.LookupVar("PATH")
+	if path == "" {
+		return nil
+	}
+	var dirs []string
+	for _, dir := range filepath.SplitList(path) {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(r.Dir, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// commands returns the names of the executables in $PATH, reading the
+// directories that have changed since it was last called.
+func (c *commandCache) commands(r *interp.Runner) []string {
+	dirs := pathDirs(r)
+	changed := false
+	if !slices.Equal(dirs, c.dirs) || c.entries == nil {
+		c.dirs, c.entries = dirs, make(map[string]*dirCommands)
+		changed = true
+	}
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		e := c.entries[dir]
+		switch {
+		case err != nil:
+			if e != nil {
+				delete(c.entries, dir)
+				changed = true
+			}
+		case e == nil || !info.ModTime().Equal(e.modTime):
+			c.entries[dir] = &dirCommands{modTime: info.ModTime(), names: executables(dir)}
+			changed = true
+		}
+	}
+	if changed {
+		seen := make(map[string]bool)
+		c.names = nil
+		for _, e := range c.entries {
+			for _, name := range e.names {
+				if !seen[name] {
+					seen[name] = true
+					c.names = append(c.names, name)
+				}
+			}
+		}
+		sort.Strings(c.names)
+	}
+	return c.names
+}
+
+// executables returns the names of the executable files in dir.
+func executables(dir string) []string {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		// Follow symbolic links to see what they name
+		info, err := os.Stat(filepath.Join(dir, file.Name()))
+		if err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0 {
+			names = append(names, file.Name())
+		}
+	}
+	return names
+}
+
+// commandNames returns the names a command word starting with prefix can be
+// completed to: aliases, functions, builtins and the executables in $PATH.
+func (c *commandCache) commandNames(r *interp.Runner, prefix string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, list := range [][]string{r.Aliases(), r.Functions(), r.Builtins(), c.commands(r)} {
+		for _, name := range list {
+			if strings.HasPrefix(name, prefix) && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
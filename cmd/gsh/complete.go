@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+// completion is the set of ways the word before the cursor can be
+// completed.
+type completion struct {
+	// names are the completions as listed on a second tab: command names,
+	// or file names with a / after directories
+	names []string
+	// rests are the text each completion adds to the word, unquoted
+	rests []string
+	// final says, for each completion, whether it finishes the word, so
+	// that a space (and any closing quote) goes after it; directories
+	// don't, so that their contents can be completed next
+	final []bool
+	// descs, if not nil, has a description to list with each completion
+	descs []string
+	// quote escapes text for the quoting in effect at the cursor, and
+	// closing is the quote that ends it
+	quote   func(s string) string
+	closing string
+	// word is the word being completed, as typed
+	word string
+}
+
+// insertion returns the text that completing to the i'th completion
+// inserts.
+func (c *completion) insertion(i int) string {
+	s := c.quote(c.rests[i])
+	if c.final[i] {
+		s += c.closing + " "
+	}
+	return s
+}
+
+// wordContext describes the word the cursor is in.
+type wordContext struct {
+	word    string // the text typed so far, as typed
+	command bool   // whether the word is in command position
+	// value is the word as the shell would read it: quotes removed and
+	// a leading ~ and $variables expanded; ok is false if that can't be
+	// told yet, as in the middle of a variable name
+	value string
+	ok    bool
+	quote byte // the quote open at the cursor, or 0
+}
+
+// commandKeywords lists the reserved words after which a command name is
+// expected.
+var commandKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "while": true, "until": true,
+	"do": true, "!": true, "{": true, "time": true,
+}
+
+// currentWord finds the word that ends at the end of line, the text before
+// the cursor.
+func currentWord(r *interp.Runner, line string) wordContext {
+	command, redirect := true, false
+	start := 0
+	var quote byte
+	var value strings.Builder
+	ok := true
+	tilde := false // the word starts with an unquoted ~
+
+	endWord := func(i int) {
+		if i > start {
+			word := line[start:i]
+			switch {
+			case redirect:
+				redirect = false
+			case command && (isAssignment(word) || commandKeywords[word]):
+			default:
+				command = false
+			}
+		}
+		start = i + 1
+		value.Reset()
+		ok, tilde = true, false
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				value.WriteByte(c)
+			}
+		case c == '\\':
+			if i+1 == len(line) {
+				break
+			}
+			i++
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", rune(line[i])) {
+				value.WriteByte('\\')
+			}
+			value.WriteByte(line[i])
+		case quote == '"' && c == '"':
+			quote = 0
+		case c == '$' && (quote == 0 || quote == '"'):
+			name, n := varName(line[i+1:])
+			if i+1+n == len(line) || n == 0 && i+1 < len(line) && line[i+1] == '{' {
+				// Still typing the name
+				ok = false
+			}
+			if n == 0 {
+				value.WriteByte(c)
+				break
+			}
+			v, _ := r.LookupVar(name)
+			value.WriteString(v)
+			i += n
+		case quote == '"':
+			value.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ' ' || c == '\t' || c == '\n':
+			endWord(i)
+		case strings.IndexByte(";&|()", c) >= 0:
+			endWord(i)
+			command, redirect = true, false
+		case c == '<' || c == '>':
+			endWord(i)
+			redirect = true
+		default:
+			if c == '~' && i == start {
+				tilde = true
+			}
+			value.WriteByte(c)
+		}
+	}
+
+	ctx := wordContext{word: line[start:], command: command && !redirect, value: value.String(), ok: ok, quote: quote}
+	if tilde {
+		ctx.value, ctx.ok = expandTilde(r, ctx.value)
+		ctx.ok = ctx.ok && ok
+	}
+	return ctx
+}
+
+// isAssignment reports whether word is a variable assignment, which may come
+// before a command name.
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	_, n := varName(name)
+	return n == len(name)
+}
+
+// varName returns the variable name at the start of s, either an identifier
+// or one in braces, and its length including the braces, or 0 if there is
+// none.
+func varName(s string) (string, int) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end <= 1 {
+			return "", 0
+		}
+		return s[1:end], end + 1
+	}
+	n := 0
+	for n < len(s) && (s[n] == '_' || 'a' <= s[n] && s[n] <= 'z' || 'A' <= s[n] && s[n] <= 'Z' ||
+		n > 0 && '0' <= s[n] && s[n] <= '9') {
+		n++
+	}
+	return s[:n], n
+}
+
+// expandTilde replaces a leading ~ or ~user in s with the home directory.
+// It reports false if the user name may not have been typed in full yet.
+func expandTilde(r *interp.Runner, s string) (string, bool) {
+	name, rest, found := strings.Cut(s[1:], "/")
+	if !found {
+		return s, false
+	}
+	if name == "" {
+		home, _ := r.LookupVar("HOME")
+		return home + "/" + rest, true
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return s, true
+	}
+	return u.HomeDir + "/" + rest, true
+}
+
+// completeFiles completes the word w as a file name, relative to the shell's
+// working directory. In command position only directories and executable
+// files are offered.
+func completeFiles(r *interp.Runner, w wordContext) *completion {
+	c := &completion{quote: quoter(w), closing: string(w.quote)}
+	if w.quote == 0 {
+		c.closing = ""
+	}
+	if !w.ok {
+		return c
+	}
+	dir, base := "", w.value
+	if i := strings.LastIndexByte(w.value, '/'); i >= 0 {
+		dir, base = w.value[:i+1], w.value[i+1:]
+	}
+	abs := dir
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(r.Dir, dir)
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return c
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		// Follow symbolic links to see what they name
+		info, err := os.Stat(filepath.Join(abs, name))
+		if err != nil {
+			continue
+		}
+		isDir := info.IsDir()
+		if w.command && !isDir && info.Mode()&0o111 == 0 {
+			continue
+		}
+		rest := name[len(base):]
+		if isDir {
+			name += "/"
+			rest += "/"
+		}
+		c.names = append(c.names, name)
+		c.rests = append(c.rests, rest)
+		c.final = append(c.final, !isDir)
+	}
+	return c
+}
+
+// completeSpec turns the words a completion spec gave for w into a
+// completion. Words that don't extend what has been typed can't be offered,
+// as completing only adds text. With no words, a spec with the default or
+// dirnames option falls back to file names.
+func completeSpec(r *interp.Runner, w wordContext, spec *interp.Completion) *completion {
+	if len(spec.Words) == 0 && (spec.Default || spec.DirNames) {
+		c := completeFiles(r, w)
+		if spec.DirNames {
+			c.keep(func(i int) bool { return !c.final[i] })
+		}
+		return c
+	}
+	c := &completion{quote: func(s string) string { return s }, closing: string(w.quote)}
+	if w.quote == 0 {
+		c.closing = ""
+	}
+	if spec.Filenames {
+		c.quote = quoter(w)
+	}
+	if !w.ok {
+		return c
+	}
+	for i, word := range spec.Words {
+		rest, ok := strings.CutPrefix(word, w.value)
+		if !ok {
+			continue
+		}
+		final := !spec.NoSpace
+		if spec.Filenames && !strings.HasSuffix(word, "/") {
+			path := word
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(r.Dir, path)
+			}
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				word += "/"
+				rest += "/"
+				final = false
+			}
+		}
+		c.names = append(c.names, word)
+		c.rests = append(c.rests, rest)
+		c.final = append(c.final, final)
+		if spec.Descriptions != nil {
+			c.descs = append(c.descs, spec.Descriptions[i])
+		}
+	}
+	sort.Sort(byName{c})
+	return c
+}
+
+// keep drops the completions for which f returns false.
+func (c *completion) keep(f func(i int) bool) {
+	n := 0
+	for i := range c.names {
+		if f(i) {
+			c.names[n], c.rests[n], c.final[n] = c.names[i], c.rests[i], c.final[i]
+			if c.descs != nil {
+				c.descs[n] = c.descs[i]
+			}
+			n++
+		}
+	}
+	c.names, c.rests, c.final = c.names[:n], c.rests[:n], c.final[:n]
+	if c.descs != nil {
+		c.descs = c.descs[:n]
+	}
+}
+
+// quoter returns a function escaping text added to the word w, so that the
+// shell reads it back as it is.
+func quoter(w wordContext) func(string) string {
+	switch w.quote {
+	case '\'':
+		// A single quote has to be added outside the quotes
+		return func(s string) string { return strings.ReplaceAll(s, "'", `'\''`) }
+	case '"':
+		return func(s string) string { return escapeChars(s, "$`\"\\") }
+	}
+	return func(s string) string {
+		s = escapeChars(s, " \t\n'\"\\$`|&;<>()*?[]{}!")
+		if w.word == "" && (strings.HasPrefix(s, "~") || strings.HasPrefix(s, "#")) {
+			// These are only special at the start of a word
+			s = `\` + s
+		}
+		return s
+	}
+}
+
+// escapeChars puts a backslash before each of the chars in s.
+func escapeChars(s, chars string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(chars, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// complete returns the completions of the word before the cursor. A command
+// name is completed from the shell's commands, unless it contains a /, when
+// it names an executable file. Other words are completed by the completion
+// spec for their command, or as file names.
+func (b *bellCompleter) complete(line string) *completion {
+	var w wordContext
+	if b.runner != nil {
+		w = currentWord(b.runner, line)
+	} else {
+		// Without a shell to complete files in, only command names
+		w = wordContext{word: line, command: true}
+	}
+	c := b.completeWord(line, w)
+	c.word = w.word
+	return c
+}
+
+// completeWord returns the completions of w, the word at the end of line.
+func (b *bellCompleter) completeWord(line string, w wordContext) *completion {
+	if b.runner != nil && !w.command {
+		if spec, ok := b.runner.Complete(context.Background(), line, len(line)); ok {
+			return completeSpec(b.runner, w, spec)
+		}
+	}
+	if b.runner != nil && (!w.command || strings.Contains(w.word, "/")) {
+		return completeFiles(b.runner, w)
+	}
+
+	c := &completion{quote: func(s string) string { return s }}
+	prefix := strings.TrimLeft(w.word, " \t")
+	if b.runner != nil {
+		for _, name := range b.commands.commandNames(b.runner, prefix) {
+			c.names = append(c.names, name)
+			c.rests = append(c.rests, name[len(prefix):])
+			c.final = append(c.final, true)
+		}
+		sort.Sort(byName{c})
+		return c
+	}
+	suggestions, _ := b.PrefixCompleterInterface.Do([]rune(w.word), len([]rune(w.word)))
+	for _, s := range suggestions {
+		// Command items end in a space, which insertion adds back
+		rest := strings.TrimSuffix(string(s), " ")
+		c.names = append(c.names, prefix+rest)
+		c.rests = append(c.rests, rest)
+		c.final = append(c.final, true)
+	}
+	sort.Sort(byName{c})
+	return c
+}
+
+// byName sorts completions by name.
+type byName struct{ *completion }
+
+func (c byName) Len() int           { return len(c.names) }
+func (c byName) Less(i, j int) bool { return c.names[i] < c.names[j] }
+func (c byName) Swap(i, j int) {
+	c.names[i], c.names[j] = c.names[j], c.names[i]
+	c.rests[i], c.rests[j] = c.rests[j], c.rests[i]
+	c.final[i], c.final[j] = c.final[j], c.final[i]
+	if c.descs != nil {
+		c.descs[i], c.descs[j] = c.descs[j], c.descs[i]
+	}
+}
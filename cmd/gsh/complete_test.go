@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"alpha.txt", "a b.txt", "it's", "$cash", ".hidden", "dir/inner", "dir/other"} {
+		name = filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	r := interp.New()
+	r.Dir = dir
+	r.SetVar("HOME", filepath.Join(dir, "dir"))
+	r.SetVar("D", dir)
+	err := r.RunString(context.Background(), `
+complete -W "start stop status" svc
+complete -W "alpha.txt dir" -o filenames open
+complete -W "a b" -o nospace ns
+complete -o dirnames cdx
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetVar("PATH", filepath.Join(dir, "dir"))
+	b := &bellCompleter{runner: r}
+
+	tests := []struct {
+		line  string
+		names []string
+		// insert is what a single completion inserts
+		insert string
+	}{
+		{"ec", []string{"echo"}, "ho "},
+		{"ls; e", []string{"echo", "exit", "export"}, ""},
+		{"cat al", []string{"alpha.txt"}, "pha.txt "},
+		{"cat a", []string{"a b.txt", "alpha.txt"}, ""},
+		{"cat a\\ ", []string{"a b.txt"}, "b.txt "},
+		{"cat 'a ", []string{"a b.txt"}, "b.txt' "},
+		{"cat \"a ", []string{"a b.txt"}, "b.txt\" "},
+		{"cat it", []string{"it's"}, "\\'s "},
+		{"cat 'it", []string{"it's"}, "'\\''s' "},
+		{"cat ", []string{"$cash", "a b.txt", "alpha.txt", "dir/", "it's", "run.sh"}, ""},
+		{"cat \\$", []string{"$cash"}, "cash "},
+		{"cat .h", []string{".hidden"}, "idden "},
+		{"cat d", []string{"dir/"}, "ir/"},
+		{"cat dir/i", []string{"inner"}, "nner "},
+		{"cat ~/o", []string{"other"}, "ther "},
+		{"cat $D/dir/o", []string{"other"}, "ther "},
+		{"cat $D", nil, ""},
+		{"echo x > al", []string{"alpha.txt"}, "pha.txt "},
+		{"./r", []string{"run.sh"}, "un.sh "},
+		{"X=1 ./", []string{"dir/", "run.sh"}, ""},
+		{"if ./r", []string{"run.sh"}, "un.sh "},
+		{"svc st", []string{"start", "status", "stop"}, ""},
+		{"svc sto", []string{"stop"}, "p "},
+		{"svc x", nil, ""},
+		{"open d", []string{"dir/"}, "ir/"},
+		{"ns a", []string{"a"}, ""},
+		{"cdx ", []string{"dir/"}, "dir/"},
+	}
+	for _, tt := range tests {
+		c := b.complete(tt.line)
+		if !slices.Equal(c.names, tt.names) {
+			t.Errorf("complete(%q) names = %q, want %q", tt.line, c.names, tt.names)
+			continue
+		}
+		if len(c.names) == 1 {
+			if got := c.insertion(0); got != tt.insert {
+				t.Errorf("complete(%q) inserts %q, want %q", tt.line, got, tt.insert)
+			}
+		}
+	}
+}
+
+func TestCompleteCommands(t *testing.T) {
+	bin1, bin2 := t.TempDir(), t.TempDir()
+	for _, name := range []string{filepath.Join(bin1, "mytool"), filepath.Join(bin2, "mytest"), filepath.Join(bin1, "mydata")} {
+		mode := os.FileMode(0755)
+		if filepath.Base(name) == "mydata" {
+			mode = 0644
+		}
+		if err := os.WriteFile(name, nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := interp.New()
+	r.SetVar("PATH", bin1)
+	b := &bellCompleter{runner: r}
+	check := func(line string, want ...string) {
+		t.Helper()
+		if got := b.complete(line).names; !slices.Equal(got, want) {
+			t.Errorf("complete(%q) names = %q, want %q", line, got, want)
+		}
+	}
+
+	check("my", "mytool")
+	// A new file changes the directory's modification time
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(bin1, "mynew"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	check("my", "mynew", "mytool")
+	r.SetVar("PATH", bin2+":"+bin1)
+	check("my", "mynew", "mytest", "mytool")
+	r.SetVar("PATH", bin2)
+	check("my", "mytest")
+
+	if err := r.RunString(context.Background(), "myfunc() { :; }; alias myalias=ls"); err != nil {
+		t.Fatal(err)
+	}
+	check("my", "myalias", "myfunc", "mytest")
+	check("ech", "echo")
+}
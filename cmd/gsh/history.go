@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/KayaLuken/golang-shell/interp"
+	"github.com/chzyer/readline"
+)
+
+// setDefaultHistFile sets HISTFILE to ~/.gsh_history unless it is already
+// set. Setting it to an empty value, in the environment or the rc file,
+// keeps the history from being saved.
+func setDefaultHistFile(r *interp.Runner) {
+	if _, ok := r.LookupVar("HISTFILE"); ok {
+		return
+	}
+	if file := homeFile(r, ".gsh_history"); file != "" {
+		r.SetVar("HISTFILE", file)
+	}
+}
+
+// syncHistory replaces readline's history with the shell's, which the
+// history builtin may have changed.
+func syncHistory(rl *readline.Instance, r *interp.Runner) {
+	rl.ResetHistory()
+	for _, line := range r.History() {
+		rl.SaveHistory(line)
+	}
+}
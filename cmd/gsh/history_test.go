@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+func TestSetDefaultHistFile(t *testing.T) {
+	r := interp.New()
+	r.SetEnv([]string{"HOME=/home/gsh"})
+	setDefaultHistFile(r)
+	if got, _ := r.LookupVar("HISTFILE"); got != "/home/gsh/.gsh_history" {
+		t.Errorf("HISTFILE = %q, want %q", got, "/home/gsh/.gsh_history")
+	}
+
+	r = interp.New()
+	r.SetEnv([]string{"HOME=/home/gsh", "HISTFILE="})
+	setDefaultHistFile(r)
+	if got, _ := r.LookupVar("HISTFILE"); got != "" {
+		t.Errorf("HISTFILE = %q, want it left empty", got)
+	}
+}
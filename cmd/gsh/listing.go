@@ -0,0 +1,53 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// queryItems is the number of completions above which the shell asks
+// before listing them, as bash's completion-query-items.
+const queryItems = 100
+
+// formatListing lays out completion names for a terminal width columns wide,
+// one line each ending in a newline. Names are listed in columns, sorted
+// down each column, as many as fit; if any have descriptions, each name
+// goes on a line of its own with its description after it.
+func formatListing(names, descs []string, width int) string {
+	if width <= 0 {
+		width = 80
+	}
+	widths := make([]int, len(names))
+	maxWidth := 0
+	for i, name := range names {
+		widths[i] = readline.Runes{}.WidthAll([]rune(name))
+		maxWidth = max(maxWidth, widths[i])
+	}
+
+	var b strings.Builder
+	if slices.ContainsFunc(descs, func(d string) bool { return d != "" }) {
+		for i, name := range names {
+			b.WriteString(name)
+			if descs[i] != "" {
+				b.WriteString(strings.Repeat(" ", maxWidth-widths[i]) + "  -- " + descs[i])
+			}
+			b.WriteByte('\n')
+		}
+		return b.String()
+	}
+
+	cols := max(1, width/(maxWidth+2))
+	rows := (len(names) + cols - 1) / cols
+	for row := 0; row < rows; row++ {
+		for i := row; i < len(names); i += rows {
+			b.WriteString(names[i])
+			if i+rows < len(names) {
+				b.WriteString(strings.Repeat(" ", maxWidth+2-widths[i]))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
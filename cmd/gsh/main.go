@@ -0,0 +1,316 @@
+// Command gsh is an interactive shell built on the interp package.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/KayaLuken/golang-shell/interp"
+	"github.com/KayaLuken/golang-shell/syntax"
+	"github.com/chzyer/readline"
+)
+
+type bellCompleter struct {
+	// PrefixCompleterInterface completes command names when there is no
+	// runner
+	readline.PrefixCompleterInterface
+	// runner is the shell whose commands, working directory and variables
+	// words are completed with
+	runner   *interp.Runner
+	commands commandCache
+	lastLine string
+	tabCount int
+	// prompt is the last line of the prompt, redrawn after listing
+	// suggestions
+	prompt string
+	// out is readline's writer, which clears the line being edited before
+	// what is written to it and draws it again after; if it is nil,
+	// listings are written to standard output, followed by the prompt
+	out io.Writer
+	// width is the width of the terminal, or 0 to ask readline for it
+	width int
+	// pending is a listing to show if the answer to the question asked
+	// before it is yes
+	pending string
+	// selecting reports whether readline is choosing from the completion
+	// menu; it is only called on readline's goroutine that handles keys
+	selecting func() bool
+	// menuOpen is set while readline is choosing from the completion menu.
+	// It is read by escReader, on readline's terminal goroutine.
+	menuOpen atomic.Bool
+}
+
+func (b *bellCompleter) Do(line []rune, pos int) (newLine [][]rune, length int) {
+	input := string(line[:pos])
+	c := b.complete(input)
+
+	// No suggestions: ring bell as before
+	if len(c.names) == 0 {
+		fmt.Print("\a")
+		b.tabCount = 0
+		b.lastLine = input
+		return nil, 0
+	}
+
+	// Track repeated tab presses for the same input
+	if input == b.lastLine {
+		b.tabCount++
+	} else {
+		b.tabCount = 1
+		b.lastLine = input
+	}
+
+	if len(c.names) == 1 {
+		return [][]rune{[]rune(c.insertion(0))}, 0
+	}
+
+	if b.runner != nil && b.runner.Option("menucomplete") && len(c.names) <= queryItems {
+		// Readline inserts any prefix the completions share, and
+		// otherwise shows them below the line; another Tab opens them
+		// as a menu, where Tab and the arrow keys choose one and Enter
+		// inserts it
+		items := make([][]rune, len(c.names))
+		for i := range c.names {
+			items[i] = []rune(c.insertion(i))
+		}
+		return items, len([]rune(c.word))
+	}
+
+	// Multiple suggestions: complete to their longest common prefix
+	lcp := c.rests[0]
+	for _, s := range c.rests[1:] {
+		lcp = commonPrefix(lcp, s)
+	}
+	if lcp != "" {
+		return [][]rune{[]rune(c.quote(lcp))}, 0
+	}
+	// Otherwise ring the bell, and list them on a second tab
+	if b.tabCount == 1 {
+		fmt.Print("\a")
+		return nil, 0
+	}
+	width := b.width
+	if width == 0 {
+		width = readline.GetScreenWidth()
+	}
+	listing := formatListing(c.names, c.descs, width)
+	if len(c.names) > queryItems {
+		b.show(string(line), fmt.Sprintf("Display all %d possibilities? (y or n)\n", len(c.names)))
+		b.pending = listing
+		return nil, 0
+	}
+	b.show(string(line), listing)
+	return nil, 0
+}
+
+// OnChange is called by readline after it has handled a key, and notes
+// whether the key opened the completion menu.
+func (b *bellCompleter) OnChange(line []rune, pos int, key rune) ([]rune, int, bool) {
+	if b.selecting != nil {
+		b.menuOpen.Store(b.selecting())
+	}
+	return nil, 0, false
+}
+
+// show writes text below line, the line being edited, and then shows the
+// line again.
+func (b *bellCompleter) show(line, text string) {
+	if b.out == nil {
+		fmt.Print("\n" + text + b.prompt + line)
+		return
+	}
+	// The line is cleared, so it is written again above the text
+	io.WriteString(b.out, b.prompt+line+"\n"+text)
+}
+
+// FilterInput takes the key typed after Do has asked whether to list the
+// completions as the answer: y or space lists them, and any other key
+// doesn't. Other keys are left to readline.
+func (b *bellCompleter) FilterInput(r rune) (rune, bool) {
+	switch r {
+	case readline.CharTab, readline.CharForward, readline.CharBackward, readline.CharNext, readline.CharPrev,
+		readline.CharLineStart, readline.CharLineEnd:
+		// These move around the menu, if it is open
+	default:
+		// Any other key closes it. OnChange isn't called for the keys
+		// that close it without editing the line, such as Enter.
+		b.menuOpen.Store(false)
+	}
+	if b.pending == "" {
+		return r, true
+	}
+	listing := b.pending
+	b.pending = ""
+	if r == 'y' || r == 'Y' || r == ' ' {
+		// The question is still shown above the line, which is
+		// replaced by the listing and drawn again after it
+		if b.out != nil {
+			io.WriteString(b.out, listing)
+		} else {
+			fmt.Print(listing)
+		}
+	}
+	return r, false
+}
+
+func main() {
+	r := interp.New()
+	// History expansion is on by default, but only at the prompt
+	r.SetOption("histexpand", true)
+	inv, err := parseInvocation(r, os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", r.Name, err)
+		os.Exit(2)
+	}
+	if strings.HasPrefix(os.Args[0], "-") {
+		inv.login = true
+	}
+	ctx := context.Background()
+
+	readProfiles(ctx, r, inv)
+	if r.Exited() {
+		os.Exit(r.Exit())
+	}
+
+	if inv.hasCommand {
+		r.SetOption("histexpand", false)
+		if len(inv.operands) > 0 {
+			r.Name, r.Params = inv.operands[0], inv.operands[1:]
+		}
+		r.RunReader(ctx, strings.NewReader(inv.command))
+		os.Exit(r.Exit())
+	}
+	if len(inv.operands) > 0 && !inv.readStdin {
+		r.SetOption("histexpand", false)
+		os.Exit(runScript(r, inv.operands[0], inv.operands[1:]))
+	}
+	r.Params = inv.operands
+	if !inv.interactive && !readline.IsTerminal(int(os.Stdin.Fd())) {
+		r.SetOption("histexpand", false)
+		r.RunReader(ctx, os.Stdin)
+		os.Exit(r.Exit())
+	}
+	r.Interactive = true
+	setDefaultHistFile(r)
+	readRC(ctx, r, inv)
+	if r.Exited() {
+		os.Exit(r.Exit())
+	}
+
+	if err := r.LoadHistory(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: history: %v\n", r.Name, err)
+	}
+
+	completer := &bellCompleter{runner: r}
+	rl, err := readline.NewEx(&readline.Config{
+		// The shell keeps the history, trimmed to $HISTSIZE, and hands it
+		// to readline after each command
+		HistoryLimit:           math.MaxInt32,
+		DisableAutoSaveHistory: true,
+		AutoComplete:           completer,
+		FuncFilterInputRune:    completer.FilterInput,
+		Listener:               completer,
+		Stdin:                  readline.NewCancelableStdin(escReader{readline.Stdin, completer}),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize readline:", err)
+		os.Exit(1)
+	}
+	completer.out = rl.Stdout()
+	completer.selecting = func() bool { return rl.Operation.IsInCompleteSelectMode() }
+
+	syncHistory(rl, r)
+
+	// src accumulates lines until they form a complete command
+	var src strings.Builder
+	for !r.Exited() {
+		if src.Len() == 0 {
+			r.RunPromptCommand(ctx)
+			setPrompt(rl, completer, primaryPrompt(r))
+		} else {
+			setPrompt(rl, completer, continuationPrompt(r))
+		}
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt && src.Len() > 0 {
+			src.Reset()
+			continue
+		}
+		if err != nil { // io.EOF, readline.ErrInterrupt
+			break
+		}
+		if r.Option("histexpand") {
+			expanded, err := r.ExpandHistory(line)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", r.Name, err)
+				src.Reset()
+				continue
+			}
+			// Show the command that will run
+			if expanded != line {
+				fmt.Println(expanded)
+			}
+			line = expanded
+		}
+		src.WriteString(strings.TrimRight(line, "\r\n") + "\n")
+		if _, err := syntax.Parse(src.String()); err == syntax.ErrIncomplete {
+			continue
+		}
+		r.AddHistory(src.String())
+		r.RunString(ctx, src.String())
+		syncHistory(rl, r)
+		src.Reset()
+	}
+	rl.Close()
+	status := r.Exit()
+	if err := r.SaveHistory(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: history: %v\n", r.Name, err)
+	}
+	os.Exit(status)
+}
+
+// escReader reads the terminal for readline, which waits for the key after
+// an Esc, as the two may start an escape sequence. While the completion menu
+// is open an Esc read on its own, as typed, is passed on as Ctrl-G, which
+// closes the menu.
+type escReader struct {
+	io.Reader
+	b *bellCompleter
+}
+
+func (e escReader) Read(p []byte) (int, error) {
+	n, err := e.Reader.Read(p)
+	if n == 1 && p[0] == readline.CharEsc && e.b.menuOpen.Load() {
+		p[0] = readline.CharBell
+	}
+	return n, err
+}
+
+// setPrompt makes prompt the one shown for the next line rl reads. Readline
+// only redraws the last line of a prompt, so any lines before it are printed
+// here.
+func setPrompt(rl *readline.Instance, completer *bellCompleter, prompt string) {
+	if i := strings.LastIndexByte(prompt, '\n'); i >= 0 {
+		fmt.Print(prompt[:i+1])
+		prompt = prompt[i+1:]
+	}
+	completer.prompt = prompt
+	rl.SetPrompt(prompt)
+}
+
+func commonPrefix(s1, s2 string) string {
+	minLen := len(s1)
+	if len(s2) < minLen {
+		minLen = len(s2)
+	}
+	for i := 0; i < minLen; i++ {
+		if s1[i] != s2[i] {
+			return s1[:i]
+		}
+	}
+	return s1[:minLen]
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+// invocation describes how the shell was started, as parsed from its
+// command line arguments.
+type invocation struct {
+	command     string // the command string given with -c
+	hasCommand  bool   // -c: run command instead of reading a script or stdin
+	readStdin   bool   // -s: read commands from stdin even if operands are given
+	interactive bool   // -i: run the REPL even if stdin isn't a terminal
+	login       bool   // -l, --login, or argv[0] starting with '-'
+	norc        bool   // --norc
+	noprofile   bool   // --noprofile
+	operands    []string
+}
+
+// parseInvocation parses the shell's command line arguments (without argv[0])
+// and applies the options they set to r.
+func parseInvocation(r *interp.Runner, args []string) (*invocation, error) {
+	inv := &invocation{}
+	i := 0
+	for ; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-" {
+			i++
+			break
+		}
+		switch arg {
+		case "--norc":
+			inv.norc = true
+			continue
+		case "--noprofile":
+			inv.noprofile = true
+			continue
+		case "--login":
+			inv.login = true
+			continue
+		}
+		if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') || strings.HasPrefix(arg, "--") {
+			if strings.HasPrefix(arg, "--") {
+				return nil, fmt.Errorf("%s: invalid option", arg)
+			}
+			break
+		}
+		on := arg[0] == '-'
+		for j := 1; j < len(arg); j++ {
+			switch c := arg[j]; c {
+			case 'c':
+				inv.hasCommand = true
+			case 's':
+				inv.readStdin = true
+			case 'i':
+				inv.interactive = true
+			case 'l':
+				inv.login = true
+			case 'o':
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("-o: option requires an argument")
+				}
+				i++
+				if err := r.SetOption(args[i], on); err != nil {
+					return nil, err
+				}
+			default:
+				name, ok := interp.OptionName(c)
+				if !ok {
+					return nil, fmt.Errorf("%c%c: invalid option", arg[0], c)
+				}
+				r.SetOption(name, on)
+			}
+		}
+	}
+	inv.operands = args[i:]
+
+	if inv.hasCommand {
+		if len(inv.operands) == 0 {
+			return nil, fmt.Errorf("-c: option requires an argument")
+		}
+		inv.command, inv.operands = inv.operands[0], inv.operands[1:]
+	}
+	return inv, nil
+}
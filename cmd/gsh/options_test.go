@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+func TestParseInvocation_CommandString(t *testing.T) {
+	r := interp.New()
+
+	inv, err := parseInvocation(r, []string{"-ec", "echo $1", "arg0", "one"})
+	if err != nil {
+		t.Fatalf("parseInvocation returned error: %v", err)
+	}
+	if !inv.hasCommand || inv.command != "echo $1" {
+		t.Errorf("command = %q (hasCommand %v), want %q", inv.command, inv.hasCommand, "echo $1")
+	}
+	if want := []string{"arg0", "one"}; !reflect.DeepEqual(inv.operands, want) {
+		t.Errorf("operands = %#v, want %#v", inv.operands, want)
+	}
+	if !r.Option("errexit") {
+		t.Errorf("-e did not set errexit")
+	}
+}
+
+func TestParseInvocation_Flags(t *testing.T) {
+	r := interp.New()
+
+	inv, err := parseInvocation(r, []string{"-l", "-i", "--norc", "--noprofile", "-o", "nounset", "-x", "+x", "-s", "--", "-a"})
+	if err != nil {
+		t.Fatalf("parseInvocation returned error: %v", err)
+	}
+	if !inv.login || !inv.interactive || !inv.norc || !inv.noprofile || !inv.readStdin {
+		t.Errorf("invocation = %+v, want login, interactive, norc, noprofile and readStdin set", inv)
+	}
+	if !r.Option("nounset") || r.Option("xtrace") {
+		t.Errorf("nounset = %v, xtrace = %v, want nounset on and xtrace off", r.Option("nounset"), r.Option("xtrace"))
+	}
+	if want := []string{"-a"}; !reflect.DeepEqual(inv.operands, want) {
+		t.Errorf("operands = %#v, want %#v", inv.operands, want)
+	}
+}
+
+func TestParseInvocation_Errors(t *testing.T) {
+	r := interp.New()
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-c"}, "-c: option requires an argument"},
+		{[]string{"-o"}, "-o: option requires an argument"},
+		{[]string{"-o", "bogus"}, "bogus: invalid option name"},
+		{[]string{"-q"}, "-q: invalid option"},
+		{[]string{"--bogus"}, "--bogus: invalid option"},
+	}
+	for _, tt := range tests {
+		_, err := parseInvocation(r, tt.args)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("parseInvocation(%q) error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
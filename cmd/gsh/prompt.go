@@ -0,0 +1,23 @@
+package main
+
+import "github.com/KayaLuken/golang-shell/interp"
+
+// primaryPrompt returns the prompt shown before a command: the expansion of
+// PS1, or "$ " ("# " for root) if it is unset.
+func primaryPrompt(r *interp.Runner) string {
+	ps1, ok := r.LookupVar("PS1")
+	if !ok {
+		ps1 = `\$ `
+	}
+	return r.ExpandPrompt(ps1)
+}
+
+// continuationPrompt returns the prompt shown while a command is incomplete:
+// the expansion of PS2, or "> " if it is unset.
+func continuationPrompt(r *interp.Runner) string {
+	ps2, ok := r.LookupVar("PS2")
+	if !ok {
+		return "> "
+	}
+	return r.ExpandPrompt(ps2)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+func TestPrompts(t *testing.T) {
+	r := interp.New()
+	want := "$ "
+	if os.Geteuid() == 0 {
+		want = "# "
+	}
+	if got := primaryPrompt(r); got != want {
+		t.Errorf("default primaryPrompt = %q, want %q", got, want)
+	}
+	if got := continuationPrompt(r); got != "> " {
+		t.Errorf("default continuationPrompt = %q, want %q", got, "> ")
+	}
+
+	r.SetVar("PS1", `[\?] $USER_X> `)
+	r.SetVar("PS2", `\s... `)
+	r.Name = "/bin/gsh"
+	if got := primaryPrompt(r); got != "[0] > " {
+		t.Errorf("primaryPrompt = %q, want %q", got, "[0] > ")
+	}
+	if got := continuationPrompt(r); got != "gsh... " {
+		t.Errorf("continuationPrompt = %q, want %q", got, "gsh... ")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+// runScript runs the commands in the file at path with args as the
+// positional parameters, and returns the status the shell exits with.
+func runScript(r *interp.Runner, path string, args []string) int {
+	full := path
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(r.Dir, full)
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		fmt.Fprintf(r.Stderr, "%s: %v\n", path, err)
+		return 127
+	}
+	defer f.Close()
+
+	r.Name, r.Params = path, args
+	r.RunReader(context.Background(), f)
+	return r.Exit()
+}
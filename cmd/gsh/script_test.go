@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+func TestRunScript_SetsPositionalParams(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.sh")
+	out := filepath.Join(dir, "out.txt")
+	body := "#!/bin/gsh\necho $0 $# $2 > " + out + "\n"
+	if err := os.WriteFile(script, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := runScript(interp.New(), script, []string{"one", "two"}); status != 0 {
+		t.Fatalf("runScript status = %d, want 0", status)
+	}
+	got, _ := os.ReadFile(out)
+	want := script + " 2 two\n"
+	if string(got) != want {
+		t.Errorf("script output = %q, want %q", got, want)
+	}
+}
+
+func TestRunScript_Missing(t *testing.T) {
+	if status := runScript(interp.New(), filepath.Join(t.TempDir(), "missing.sh"), nil); status != 127 {
+		t.Errorf("runScript status = %d, want 127", status)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+// readProfiles runs the startup files of a login shell: /etc/profile and
+// then ~/.profile. It does nothing if the shell isn't a login shell, or was
+// started with --noprofile.
+func readProfiles(ctx context.Context, r *interp.Runner, inv *invocation) {
+	if !inv.login || inv.noprofile {
+		return
+	}
+	sourceStartupFile(ctx, r, "/etc/profile")
+	sourceStartupFile(ctx, r, homeFile(r, ".profile"))
+}
+
+// readRC runs the rc file of an interactive shell, unless it was started
+// with --norc.
+func readRC(ctx context.Context, r *interp.Runner, inv *invocation) {
+	if inv.norc {
+		return
+	}
+	sourceStartupFile(ctx, r, rcFile(r))
+}
+
+// rcFile returns the path of the interactive rc file: $ENV, after parameter
+// expansion, if it is set and non-empty, or else ~/.gshrc.
+func rcFile(r *interp.Runner) string {
+	if env, ok := r.LookupVar("ENV"); ok && env != "" {
+		return os.Expand(env, func(name string) string {
+			value, _ := r.LookupVar(name)
+			return value
+		})
+	}
+	return homeFile(r, ".gshrc")
+}
+
+// homeFile returns the path of name in the user's home directory, or "" if
+// it isn't known.
+func homeFile(r *interp.Runner, name string) string {
+	home, ok := r.LookupVar("HOME")
+	if !ok || home == "" {
+		var err error
+		if home, err = os.UserHomeDir(); err != nil {
+			return ""
+		}
+	}
+	return filepath.Join(home, name)
+}
+
+// sourceStartupFile runs the startup file at path in r. A file that doesn't
+// exist is skipped; one that can't be read is reported. Syntax errors are
+// reported by Source with the file name and line.
+func sourceStartupFile(ctx context.Context, r *interp.Runner, path string) {
+	if path == "" {
+		return
+	}
+	err := r.Source(ctx, path)
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(r.Stderr, "%s: %s: %v\n", r.Name, path, pathErr.Err)
+	}
+}
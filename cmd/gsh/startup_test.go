@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KayaLuken/golang-shell/interp"
+)
+
+func TestRCFile(t *testing.T) {
+	r := interp.New()
+	r.SetEnv([]string{"HOME=/home/gsh"})
+	if got, want := rcFile(r), "/home/gsh/.gshrc"; got != want {
+		t.Errorf("rcFile() = %q, want %q", got, want)
+	}
+	r.SetEnv([]string{"HOME=/home/gsh", "ENV=$HOME/custom.rc"})
+	if got, want := rcFile(r), "/home/gsh/custom.rc"; got != want {
+		t.Errorf("rcFile() with ENV = %q, want %q", got, want)
+	}
+}
+
+func TestSourceStartupFile(t *testing.T) {
+	home := t.TempDir()
+	os.WriteFile(filepath.Join(home, ".profile"), []byte("GSH_PROFILE=read\n"), 0644)
+	os.WriteFile(filepath.Join(home, ".gshrc"), []byte("alias hi='echo hi'\ngreet() { echo hello; }\n"), 0644)
+
+	var out, errOut strings.Builder
+	r := interp.New()
+	r.Stdout, r.Stderr, r.Name = &out, &errOut, "gsh"
+	r.SetEnv([]string{"HOME=" + home})
+	sourceStartupFile(context.Background(), r, homeFile(r, ".profile"))
+	sourceStartupFile(context.Background(), r, filepath.Join(home, "missing"))
+	readRC(context.Background(), r, &invocation{})
+	r.RunString(context.Background(), "echo $GSH_PROFILE; hi; greet")
+	if want := "read\nhi\nhello\n"; out.String() != want {
+		t.Errorf("output after startup = %q, want %q", out.String(), want)
+	}
+	if errOut.Len() != 0 {
+		t.Errorf("stderr = %q, want nothing for a missing file", errOut.String())
+	}
+
+	// Syntax errors are reported with the file and line
+	rc := filepath.Join(home, ".gshrc")
+	os.WriteFile(rc, []byte("echo first\n\nif true; then\n  echo x\nesac\necho not run\n"), 0644)
+	out.Reset()
+	readRC(context.Background(), r, &invocation{})
+	if want := "first\n"; out.String() != want {
+		t.Errorf("output of a broken rc file = %q, want %q", out.String(), want)
+	}
+	if want := "gsh: " + rc + ": line 5: syntax error near unexpected token `esac'\n"; errOut.String() != want {
+		t.Errorf("stderr = %q, want %q", errOut.String(), want)
+	}
+
+	// --norc and --noprofile skip the files
+	out.Reset()
+	errOut.Reset()
+	readRC(context.Background(), r, &invocation{norc: true})
+	readProfiles(context.Background(), r, &invocation{login: true, noprofile: true})
+	readProfiles(context.Background(), r, &invocation{})
+	if out.Len() != 0 || errOut.Len() != 0 {
+		t.Errorf("skipped startup files wrote %q and %q", out.String(), errOut.String())
+	}
+}
@@ -0,0 +1,623 @@
+package gitstatus
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// repo reads a git repository's files directly, for the prompt's git
+// segment: it needs the branch, the index and a few objects, and running git
+// for them each time the prompt is drawn would be slow. Only SHA-1
+// repositories are understood.
+type repo struct {
+	gitDir   string // the .git directory
+	workTree string // the directory the .git belongs to
+	// commonDir holds the objects, refs and config, which the linked
+	// worktrees of a repository share; it is gitDir for the main worktree.
+	commonDir string
+	packs     []*pack
+}
+
+// Hash is a SHA-1 object name.
+type Hash [20]byte
+
+func (h Hash) String() string { return hex.EncodeToString(h[:]) }
+
+// parseHash parses a hex object name.
+func parseHash(s string) (Hash, bool) {
+	var h Hash
+	if len(s) != 40 {
+		return h, false
+	}
+	_, err := hex.Decode(h[:], []byte(s))
+	return h, err == nil
+}
+
+// findRepo returns the repository dir is in, looking for a .git
+// directory, or a .git file naming one, in dir and its parents.
+func findRepo(dir string) (*repo, bool) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			gitDir := dotGit
+			if !info.IsDir() {
+				// A worktree or submodule: .git holds "gitdir: path"
+				data, err := os.ReadFile(dotGit)
+				path, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+				if err != nil || !ok {
+					return nil, false
+				}
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				gitDir = path
+			}
+			repo := &repo{gitDir: gitDir, workTree: dir, commonDir: gitDir}
+			if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+				common := strings.TrimSpace(string(data))
+				if !filepath.IsAbs(common) {
+					common = filepath.Join(gitDir, common)
+				}
+				repo.commonDir = filepath.Clean(common)
+			}
+			return repo, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, false
+		}
+		dir = parent
+	}
+}
+
+// head returns the branch HEAD points at, without refs/heads/, or "" if
+// HEAD is detached, and the commit it names.
+func (g *repo) head() (branch string, commit Hash, err error) {
+	data, err := os.ReadFile(filepath.Join(g.gitDir, "HEAD"))
+	if err != nil {
+		return "", commit, err
+	}
+	s := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(s, "ref: "); ok {
+		branch = strings.TrimPrefix(ref, "refs/heads/")
+		// An unborn branch has no commit yet
+		commit, _ = g.resolveRef(ref)
+		return branch, commit, nil
+	}
+	commit, ok := parseHash(s)
+	if !ok {
+		return "", commit, fmt.Errorf("HEAD: bad object name")
+	}
+	return "", commit, nil
+}
+
+// resolveRef returns the commit a ref such as refs/heads/main names, looking
+// in its loose file and then in packed-refs.
+func (g *repo) resolveRef(ref string) (Hash, bool) {
+	for range 10 {
+		data, err := os.ReadFile(g.refPath(ref))
+		if err != nil {
+			break
+		}
+		s := strings.TrimSpace(string(data))
+		if next, ok := strings.CutPrefix(s, "ref: "); ok {
+			ref = next
+			continue
+		}
+		return parseHash(s)
+	}
+	f, err := os.Open(filepath.Join(g.commonDir, "packed-refs"))
+	if err != nil {
+		return Hash{}, false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		hash, name, ok := strings.Cut(sc.Text(), " ")
+		if ok && name == ref {
+			return parseHash(hash)
+		}
+	}
+	return Hash{}, false
+}
+
+// refPath returns the file holding a loose ref. Refs other than HEAD-like
+// pseudo refs live in the common directory.
+func (g *repo) refPath(ref string) string {
+	if strings.HasPrefix(ref, "refs/") {
+		return filepath.Join(g.commonDir, filepath.FromSlash(ref))
+	}
+	return filepath.Join(g.gitDir, ref)
+}
+
+// upstream returns the remote-tracking ref that branch follows according
+// to the repository's config, or "" if it has none.
+func (g *repo) upstream(branch string) string {
+	f, err := os.Open(filepath.Join(g.commonDir, "config"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var remote, merge string
+	inSection := false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = line == `[branch "`+branch+`"]`
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inSection || !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "remote":
+			remote = strings.Trim(strings.TrimSpace(value), `"`)
+		case "merge":
+			merge = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	switch {
+	case remote == "" || merge == "":
+		return ""
+	case remote == ".":
+		return merge
+	}
+	return "refs/remotes/" + remote + "/" + strings.TrimPrefix(merge, "refs/heads/")
+}
+
+// Object types, as numbered in pack files.
+const (
+	objCommit   = 1
+	objTree     = 2
+	objBlob     = 3
+	objTag      = 4
+	objOfsDelta = 6
+	objRefDelta = 7
+)
+
+var typeNames = map[string]int{"commit": objCommit, "tree": objTree, "blob": objBlob, "tag": objTag}
+
+// errNoObject is returned for an object the repository doesn't have.
+var errNoObject = errors.New("object not found")
+
+// readObject returns the type and contents of an object, loose or packed.
+func (g *repo) readObject(h Hash) (int, []byte, error) {
+	name := h.String()
+	f, err := os.Open(filepath.Join(g.commonDir, "objects", name[:2], name[2:]))
+	if err == nil {
+		defer f.Close()
+		zr, err := zlib.NewReader(f)
+		if err != nil {
+			return 0, nil, err
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			return 0, nil, err
+		}
+		header, body, ok := bytes.Cut(data, []byte{0})
+		typ, _, _ := strings.Cut(string(header), " ")
+		if !ok || typeNames[typ] == 0 {
+			return 0, nil, fmt.Errorf("%s: bad object header", name)
+		}
+		return typeNames[typ], body, nil
+	}
+	if err := g.loadPacks(); err != nil {
+		return 0, nil, err
+	}
+	for _, p := range g.packs {
+		if off, ok := p.find(h); ok {
+			return p.readAt(g, off)
+		}
+	}
+	return 0, nil, fmt.Errorf("%s: %w", name, errNoObject)
+}
+
+// commit returns the tree and parents of a commit, and its committer time.
+func (g *repo) commit(h Hash) (tree Hash, parents []Hash, time int64, err error) {
+	typ, data, err := g.readObject(h)
+	if err != nil {
+		return tree, nil, 0, err
+	}
+	if typ != objCommit {
+		return tree, nil, 0, fmt.Errorf("%s: not a commit", h)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			tree, _ = parseHash(value)
+		case "parent":
+			if p, ok := parseHash(value); ok {
+				parents = append(parents, p)
+			}
+		case "committer":
+			// Name <email> seconds zone
+			fields := strings.Fields(value)
+			if len(fields) >= 2 {
+				time, _ = strconv.ParseInt(fields[len(fields)-2], 10, 64)
+			}
+		}
+	}
+	return tree, parents, time, nil
+}
+
+// treeFiles adds the files in tree h and its subtrees to files, by their
+// slash-separated path under prefix, as their object name and mode.
+func (g *repo) treeFiles(h Hash, prefix string, files map[string]indexEntry) error {
+	typ, data, err := g.readObject(h)
+	if err != nil {
+		return err
+	}
+	if typ != objTree {
+		return fmt.Errorf("%s: not a tree", h)
+	}
+	for len(data) > 0 {
+		// mode SP name NUL hash
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || len(data) < nul+21 {
+			return fmt.Errorf("%s: bad tree", h)
+		}
+		mode, _ := strconv.ParseUint(string(data[:sp]), 8, 32)
+		path := prefix + string(data[sp+1:nul])
+		var entry Hash
+		copy(entry[:], data[nul+1:nul+21])
+		data = data[nul+21:]
+		if mode == 0o40000 {
+			if err := g.treeFiles(entry, path+"/", files); err != nil {
+				return err
+			}
+			continue
+		}
+		files[path] = indexEntry{path: path, hash: entry, mode: uint32(mode)}
+	}
+	return nil
+}
+
+// pack is a pack file and its index. The pack is kept open until the
+// repository's packs are closed.
+type pack struct {
+	path    string // the .pack file
+	file    *os.File
+	fanout  [256]uint32
+	names   []byte // the sorted object names
+	offsets []byte // their 4-byte offsets
+	large   []byte // 8-byte offsets for packs over 2GB
+}
+
+// loadPacks opens the repository's pack files and reads their indexes, once
+// until closePacks is called.
+func (g *repo) loadPacks() error {
+	if g.packs != nil {
+		return nil
+	}
+	g.packs = []*pack{}
+	idxs, _ := filepath.Glob(filepath.Join(g.commonDir, "objects", "pack", "*.idx"))
+	for _, idx := range idxs {
+		p, err := openPack(idx)
+		if err != nil {
+			return err
+		}
+		g.packs = append(g.packs, p)
+	}
+	return nil
+}
+
+// closePacks closes the pack files, so that they are opened again, as they
+// may have been repacked, the next time an object is read from them.
+func (g *repo) closePacks() {
+	for _, p := range g.packs {
+		p.file.Close()
+	}
+	g.packs = nil
+}
+
+// openPack reads a version 2 pack index and opens its pack.
+func openPack(idx string) (*pack, error) {
+	data, err := os.ReadFile(idx)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 8+256*4 || string(data[:4]) != "\xfftOc" || binary.BigEndian.Uint32(data[4:]) != 2 {
+		return nil, fmt.Errorf("%s: unsupported pack index", idx)
+	}
+	p := &pack{path: strings.TrimSuffix(idx, ".idx") + ".pack"}
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(data[8+4*i:])
+	}
+	n := int(p.fanout[255])
+	names := 8 + 256*4
+	offsets := names + n*20 + n*4 // after the names and their CRCs
+	if len(data) < offsets+n*4 {
+		return nil, fmt.Errorf("%s: truncated pack index", idx)
+	}
+	p.names = data[names : names+n*20]
+	p.offsets = data[offsets : offsets+n*4]
+	p.large = data[offsets+n*4:]
+	if p.file, err = os.Open(p.path); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// find returns the offset of an object in the pack.
+func (p *pack) find(h Hash) (int64, bool) {
+	lo := 0
+	if h[0] > 0 {
+		lo = int(p.fanout[h[0]-1])
+	}
+	hi := int(p.fanout[h[0]])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.names[(lo+i)*20:(lo+i+1)*20], h[:]) >= 0
+	})
+	if i == hi || !bytes.Equal(p.names[i*20:(i+1)*20], h[:]) {
+		return 0, false
+	}
+	off := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if off&0x80000000 == 0 {
+		return int64(off), true
+	}
+	k := int(off&0x7fffffff) * 8
+	if k+8 > len(p.large) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[k:])), true
+}
+
+// readAt returns the type and contents of the object at off in the pack,
+// applying deltas.
+func (p *pack) readAt(g *repo, off int64) (int, []byte, error) {
+	return p.readEntry(g, off, 0)
+}
+
+// maxDeltaChain limits how many deltas readEntry follows, in case of a
+// corrupt pack.
+const maxDeltaChain = 10000
+
+func (p *pack) readEntry(g *repo, off int64, depth int) (int, []byte, error) {
+	if depth > maxDeltaChain {
+		return 0, nil, fmt.Errorf("%s: delta chain too long", p.path)
+	}
+	br := bufio.NewReader(io.NewSectionReader(p.file, off, math.MaxInt64-off))
+	c, err := br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	// The type and size: 3 bits and 4, then 7 more bits of size a byte
+	typ := int(c>>4) & 7
+	size := uint64(c & 0x0f)
+	for shift := 4; c&0x80 != 0; shift += 7 {
+		if c, err = br.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+		size |= uint64(c&0x7f) << shift
+	}
+
+	var base func() (int, []byte, error)
+	switch typ {
+	case objOfsDelta:
+		// The base's offset back from this entry, in a big-endian
+		// base-128 encoding that adds one for each continuation
+		if c, err = br.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+		back := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = br.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			back = (back+1)<<7 | int64(c&0x7f)
+		}
+		base = func() (int, []byte, error) { return p.readEntry(g, off-back, depth+1) }
+	case objRefDelta:
+		var h Hash
+		if _, err := io.ReadFull(br, h[:]); err != nil {
+			return 0, nil, err
+		}
+		base = func() (int, []byte, error) { return g.readObject(h) }
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return 0, nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(zr, data); err != nil {
+		return 0, nil, err
+	}
+	if base == nil {
+		return typ, data, nil
+	}
+	baseType, baseData, err := base()
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err = applyDelta(baseData, data)
+	return baseType, data, err
+}
+
+// applyDelta rebuilds an object from its base and a delta.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	errBad := errors.New("bad delta")
+	varint := func() (uint64, bool) {
+		var v uint64
+		for shift := 0; len(delta) > 0; shift += 7 {
+			c := delta[0]
+			delta = delta[1:]
+			v |= uint64(c&0x7f) << shift
+			if c&0x80 == 0 {
+				return v, true
+			}
+		}
+		return 0, false
+	}
+	baseSize, ok1 := varint()
+	size, ok2 := varint()
+	if !ok1 || !ok2 || baseSize != uint64(len(base)) {
+		return nil, errBad
+	}
+	out := make([]byte, 0, size)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		if op&0x80 == 0 {
+			// Insert the next op bytes
+			n := int(op)
+			if n == 0 || n > len(delta) {
+				return nil, errBad
+			}
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+			continue
+		}
+		// Copy: bits 0-3 say which offset bytes follow, 4-6 which size
+		// bytes
+		var off, n uint64
+		for i := range 7 {
+			if op&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, errBad
+			}
+			if i < 4 {
+				off |= uint64(delta[0]) << (8 * i)
+			} else {
+				n |= uint64(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+		if n == 0 {
+			n = 0x10000
+		}
+		if off+n > uint64(len(base)) {
+			return nil, errBad
+		}
+		out = append(out, base[off:off+n]...)
+	}
+	if uint64(len(out)) != size {
+		return nil, errBad
+	}
+	return out, nil
+}
+
+// indexEntry is a file in the index, or in a tree.
+type indexEntry struct {
+	path  string
+	hash  Hash
+	mode  uint32
+	stage int // nonzero for the sides of an unresolved conflict
+	// The file's size and modification time when it was added, which
+	// tell that it hasn't changed without reading it
+	size  uint32
+	mtime int64 // nanoseconds
+}
+
+// readIndex reads the entries of the index, versions 2 to 4. A repository
+// that has never had a file added has no index, which is empty.
+func (g *repo) readIndex() ([]indexEntry, error) {
+	data, err := os.ReadFile(filepath.Join(g.gitDir, "index"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	errBad := errors.New("index: bad format")
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, errBad
+	}
+	version := binary.BigEndian.Uint32(data[4:])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("index: unsupported version %d", version)
+	}
+	n := int(binary.BigEndian.Uint32(data[8:]))
+	entries := make([]indexEntry, 0, n)
+	pos := 12
+	prev := ""
+	for range n {
+		if pos+62 > len(data) {
+			return nil, errBad
+		}
+		e := data[pos:]
+		entry := indexEntry{
+			mtime: int64(binary.BigEndian.Uint32(e[8:]))*1e9 + int64(binary.BigEndian.Uint32(e[12:])),
+			mode:  binary.BigEndian.Uint32(e[24:]),
+			size:  binary.BigEndian.Uint32(e[36:]),
+		}
+		copy(entry.hash[:], e[40:60])
+		flags := binary.BigEndian.Uint16(e[60:])
+		entry.stage = int(flags>>12) & 3
+		start := 62
+		if flags&0x4000 != 0 && version >= 3 {
+			start += 2 // extended flags
+		}
+		if pos+start > len(data) {
+			return nil, errBad
+		}
+		rest := data[pos+start:]
+		if version == 4 {
+			// The name drops a number of bytes from the end of the
+			// previous one, encoded as pack offsets are, and adds a
+			// suffix
+			if len(rest) == 0 {
+				return nil, errBad
+			}
+			strip := uint(rest[0] & 0x7f)
+			i := 0
+			for rest[i]&0x80 != 0 {
+				if i++; i == len(rest) {
+					return nil, errBad
+				}
+				strip = (strip+1)<<7 | uint(rest[i]&0x7f)
+			}
+			rest = rest[i+1:]
+			nul := bytes.IndexByte(rest, 0)
+			if nul < 0 || int(strip) > len(prev) {
+				return nil, errBad
+			}
+			entry.path = prev[:len(prev)-int(strip)] + string(rest[:nul])
+			pos += start + i + 1 + nul + 1
+		} else {
+			nul := bytes.IndexByte(rest, 0)
+			if nul < 0 {
+				return nil, errBad
+			}
+			entry.path = string(rest[:nul])
+			// Entries are padded with NULs to a multiple of 8 bytes
+			pos += (start + nul + 8) &^ 7
+		}
+		prev = entry.path
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// hashBlob returns the object name data would have as a blob.
+func hashBlob(data []byte) Hash {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(data))
+	h.Write(data)
+	var sum Hash
+	h.Sum(sum[:0])
+	return sum
+}
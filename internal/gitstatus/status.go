@@ -0,0 +1,509 @@
+// Package gitstatus finds the state of a git repository that a shell prompt
+// shows: the branch, whether there are staged, unstaged and untracked
+// changes, and how far the branch is ahead of and behind its upstream. It
+// reads the repository's files itself rather than running git.
+package gitstatus
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Status is what a prompt shows about a repository.
+type Status struct {
+	Branch   string // "" when HEAD is detached
+	Commit   Hash
+	Staged   bool // the index differs from HEAD
+	Unstaged bool // the work tree differs from the index
+	// Untracked is set if there are files that are neither in the index
+	// nor ignored
+	Untracked bool
+	// Upstream is set if the branch has one; Ahead and Behind count the
+	// commits only the branch and only its upstream have
+	Upstream      bool
+	Ahead, Behind int
+}
+
+// String formats the status as __git_ps1 does with its dirty state,
+// untracked files and verbose upstream options: the branch, or the
+// abbreviated commit in parentheses, then * for unstaged changes, + for
+// staged ones and % for untracked files, then u+N-M for the commits ahead
+// of and behind the upstream, or u= if there are none.
+func (s Status) String() string {
+	var b strings.Builder
+	if s.Branch != "" {
+		b.WriteString(s.Branch)
+	} else {
+		b.WriteString("(" + s.Commit.String()[:7] + "...)")
+	}
+	var flags string
+	if s.Unstaged {
+		flags += "*"
+	}
+	if s.Staged {
+		flags += "+"
+	}
+	if s.Untracked {
+		flags += "%"
+	}
+	if flags != "" {
+		b.WriteString(" " + flags)
+	}
+	if s.Upstream {
+		switch {
+		case s.Ahead == 0 && s.Behind == 0:
+			b.WriteString(" u=")
+		case s.Behind == 0:
+			fmt.Fprintf(&b, " u+%d", s.Ahead)
+		case s.Ahead == 0:
+			fmt.Fprintf(&b, " u-%d", s.Behind)
+		default:
+			fmt.Fprintf(&b, " u+%d-%d", s.Ahead, s.Behind)
+		}
+	}
+	return b.String()
+}
+
+// Cache keeps what was found out about repositories, so that drawing the
+// prompt again doesn't walk the work tree and the commit history each time.
+// It is safe for concurrent use.
+type Cache struct {
+	mu sync.Mutex
+	// dirs holds the .git directory of the repository each directory is
+	// in
+	dirs map[string]string
+	// repos holds the repositories, by .git directory
+	repos map[string]*cacheEntry
+}
+
+// cacheEntry is a repository and its last status. The status stays valid
+// while its stamp does: the index, HEAD, the branch and its upstream, and
+// the packs are unchanged. Whether tracked files have been modified is
+// checked each time, as that doesn't show in the index until they're added,
+// and the work tree is searched for untracked files again when a directory
+// or ignore file it looked at changes.
+type cacheEntry struct {
+	repo   *repo
+	stamp  string
+	status Status
+	index  []indexEntry
+	// indexErr is set if the index couldn't be read, and then whether
+	// there are changes isn't known
+	indexErr error
+	// walked holds the stamps of the directories and ignore files the
+	// search for untracked files read
+	walked map[string]string
+	// verified holds the size and modification time of files whose
+	// contents were found to match the index although their stat
+	// information didn't
+	verified map[string]fileStat
+}
+
+type fileStat struct {
+	size  int64
+	mtime int64
+}
+
+// NewCache returns an empty cache.
+func NewCache() *Cache {
+	return &Cache{
+		dirs:  make(map[string]string),
+		repos: make(map[string]*cacheEntry),
+	}
+}
+
+// Status returns the status of the git repository dir is in, or false if
+// it isn't in one.
+func (c *Cache) Status(dir string) (Status, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := c.repos[c.dirs[dir]]
+	if entry != nil {
+		if _, err := os.Stat(entry.repo.gitDir); err != nil {
+			entry.repo.closePacks()
+			delete(c.repos, entry.repo.gitDir)
+			entry = nil
+		}
+	}
+	if entry == nil {
+		repo, ok := findRepo(dir)
+		if !ok {
+			delete(c.dirs, dir)
+			return Status{}, false
+		}
+		c.dirs[dir] = repo.gitDir
+		if entry = c.repos[repo.gitDir]; entry == nil {
+			entry = &cacheEntry{repo: repo}
+			c.repos[repo.gitDir] = entry
+		}
+	}
+	repo := entry.repo
+
+	branch, commit, err := repo.head()
+	if err != nil {
+		return Status{}, false
+	}
+	upstream := ""
+	var upstreamCommit Hash
+	if branch != "" {
+		if upstream = repo.upstream(branch); upstream != "" {
+			upstreamCommit, _ = repo.resolveRef(upstream)
+		}
+	}
+	stamp := fmt.Sprintf("%s %s %s %s %s %s", fileStamp(filepath.Join(repo.gitDir, "index")), branch, commit,
+		upstreamCommit, upstream, fileStamp(filepath.Join(repo.commonDir, "objects", "pack")))
+
+	if entry.stamp != stamp {
+		// Packs may have been repacked, and the index rewritten
+		repo.closePacks()
+		entry.stamp, entry.walked = stamp, nil
+		entry.verified = make(map[string]fileStat)
+		entry.status = Status{Branch: branch, Commit: commit, Upstream: upstream != ""}
+		if entry.index, entry.indexErr = repo.readIndex(); entry.indexErr == nil {
+			entry.status.Staged = repo.stagedChanges(commit, entry.index)
+		}
+		if upstream != "" {
+			entry.status.Ahead, entry.status.Behind = repo.aheadBehind(commit, upstreamCommit)
+		}
+	}
+	if entry.indexErr != nil {
+		// Show no changes rather than guess at them
+		return entry.status, true
+	}
+	if entry.walked == nil || walkChanged(entry.walked) {
+		entry.walked = make(map[string]string)
+		entry.status.Untracked = repo.hasUntracked(entry.index, entry.walked)
+	}
+	status := entry.status
+	status.Unstaged = repo.unstagedChanges(entry)
+	return status, true
+}
+
+// fileStamp identifies a version of a file by its size and modification
+// time.
+func fileStamp(name string) string {
+	info, err := os.Stat(name)
+	if err != nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+}
+
+// walkChanged reports whether any of the files in stamps has changed.
+func walkChanged(stamps map[string]string) bool {
+	for name, stamp := range stamps {
+		if fileStamp(name) != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// stagedChanges reports whether the index differs from the tree of commit,
+// or has unresolved conflicts.
+func (g *repo) stagedChanges(commit Hash, index []indexEntry) bool {
+	tree := make(map[string]indexEntry)
+	if commit != (Hash{}) {
+		treeHash, _, _, err := g.commit(commit)
+		if err != nil || g.treeFiles(treeHash, "", tree) != nil {
+			return false
+		}
+	}
+	n := 0
+	for _, e := range index {
+		if e.stage != 0 {
+			return true
+		}
+		t, ok := tree[e.path]
+		if !ok || t.hash != e.hash || t.mode != e.mode {
+			return true
+		}
+		n++
+	}
+	return n != len(tree)
+}
+
+// unstagedChanges reports whether any tracked file in the work tree differs
+// from the index.
+func (g *repo) unstagedChanges(entry *cacheEntry) bool {
+	for _, e := range entry.index {
+		if e.stage != 0 || e.mode == 0o160000 {
+			// Conflicts show as staged changes, and submodules
+			// aren't looked into
+			continue
+		}
+		name := filepath.Join(g.workTree, filepath.FromSlash(e.path))
+		info, err := os.Lstat(name)
+		if err != nil {
+			return true
+		}
+		stat := fileStat{info.Size(), info.ModTime().UnixNano()}
+		if uint32(stat.size) == e.size && stat.mtime == e.mtime || entry.verified[e.path] == stat {
+			continue
+		}
+		var data []byte
+		switch {
+		case info.Mode().IsRegular() && e.mode&0o170000 == 0o100000:
+			if exec := info.Mode()&0o100 != 0; exec != (e.mode&0o100 != 0) {
+				return true
+			}
+			data, err = os.ReadFile(name)
+		case info.Mode()&os.ModeSymlink != 0 && e.mode == 0o120000:
+			var target string
+			target, err = os.Readlink(name)
+			data = []byte(target)
+		default:
+			return true
+		}
+		if err != nil || hashBlob(data) != e.hash {
+			return true
+		}
+		entry.verified[e.path] = stat
+	}
+	return false
+}
+
+// hasUntracked reports whether the work tree has a file that is neither in
+// the index nor ignored by .gitignore files or info/exclude. The stamps of
+// the files it reads are added to walked.
+func (g *repo) hasUntracked(index []indexEntry, walked map[string]string) bool {
+	tracked := make(map[string]bool, len(index))
+	for _, e := range index {
+		tracked[e.path] = true
+	}
+	exclude := filepath.Join(g.commonDir, "info", "exclude")
+	walked[exclude] = fileStamp(exclude)
+	rules := readIgnore(exclude, "")
+	return g.findUntracked("", rules, tracked, walked)
+}
+
+// findUntracked looks for an untracked file in the directory dir of the work
+// tree, given as a slash-separated path, "" for the top.
+func (g *repo) findUntracked(dir string, rules []ignoreRule, tracked map[string]bool, walked map[string]string) bool {
+	abs := filepath.Join(g.workTree, filepath.FromSlash(dir))
+	ignore := filepath.Join(abs, ".gitignore")
+	walked[abs], walked[ignore] = fileStamp(abs), fileStamp(ignore)
+	rules = append(rules, readIgnore(ignore, dir)...)
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return false
+	}
+	for _, de := range entries {
+		name := path.Join(dir, de.Name())
+		switch {
+		case de.Name() == ".git" || tracked[name]:
+		case de.IsDir():
+			if ignored(rules, name, true) {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(abs, de.Name(), ".git")); err == nil {
+				// A repository of its own that isn't a submodule
+				return true
+			}
+			if g.findUntracked(name, rules, tracked, walked) {
+				return true
+			}
+		case !ignored(rules, name, false):
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreRule is a pattern from a .gitignore file.
+type ignoreRule struct {
+	base    string // the directory of the .gitignore, "" for the top
+	re      *regexp.Regexp
+	negate  bool // a ! pattern, which re-includes what matches
+	dirOnly bool // a pattern ending in /, which only matches directories
+	// anchored patterns contain a slash and match the path from base; the
+	// others match the last element at any depth
+	anchored bool
+}
+
+// readIgnore reads the patterns in an ignore file for the directory
+// base. A missing file has none.
+func readIgnore(name, base string) []ignoreRule {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var rules []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		// Trailing blanks don't count unless escaped
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+			line = line[:len(line)-1]
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if rule.negate = line[0] == '!'; rule.negate {
+			line = line[1:]
+		}
+		if rule.dirOnly = strings.HasSuffix(line, "/"); rule.dirOnly {
+			line = strings.TrimRight(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		if rule.re, err = regexp.Compile(globRegexp(line)); err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// globRegexp translates a gitignore pattern into a regular expression
+// matching the same slash-separated paths.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && i > 0 && glob[i-1] == '/':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == 0 && i+2 < len(glob) {
+				// A ] right after [ is part of the set
+				end = strings.IndexByte(glob[i+2:], ']') + 1
+			}
+			if end <= 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			set := glob[i+1 : i+1+end]
+			if set[0] == '!' {
+				set = "^" + set[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(set, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// ignored reports whether the path name, relative to the top of the
+// work tree, is ignored by rules: the last rule matching it decides.
+func ignored(rules []ignoreRule, name string, isDir bool) bool {
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel := name
+		if rule.base != "" {
+			var ok bool
+			if rel, ok = strings.CutPrefix(name, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if !rule.anchored {
+			rel = path.Base(rel)
+		}
+		if rule.re.MatchString(rel) {
+			return !rule.negate
+		}
+	}
+	return false
+}
+
+// aheadBehind counts the commits reachable from local but not upstream, and
+// from upstream but not local, as git rev-list --left-right --count does.
+// It walks back from both, newest commit first, marking each commit with the
+// sides it is reachable from, until every commit left to visit is reachable
+// from both. A commit that is marked from another side after it was visited
+// is visited again, to pass the mark on to its ancestors.
+func (g *repo) aheadBehind(local, upstream Hash) (ahead, behind int) {
+	const (
+		fromLocal = 1 << iota
+		fromUpstream
+		fromBoth = fromLocal | fromUpstream
+	)
+	type commit struct {
+		time    int64
+		parents []Hash
+	}
+	flags := make(map[Hash]int)
+	commits := make(map[Hash]*commit)
+	queued := make(map[Hash]bool)
+	var queue []Hash
+	push := func(h Hash, f int) {
+		old := flags[h]
+		if h == (Hash{}) || old|f == old {
+			return
+		}
+		if commits[h] == nil {
+			_, parents, t, err := g.commit(h)
+			if err != nil {
+				return
+			}
+			commits[h] = &commit{t, parents}
+		}
+		flags[h] = old | f
+		if !queued[h] {
+			queued[h] = true
+			queue = append(queue, h)
+		}
+	}
+	push(local, fromLocal)
+	push(upstream, fromUpstream)
+	for {
+		// Stop once only commits both sides reach are left
+		newest, live := -1, false
+		for i, h := range queue {
+			if flags[h] != fromBoth {
+				live = true
+			}
+			if newest < 0 || commits[h].time > commits[queue[newest]].time {
+				newest = i
+			}
+		}
+		if !live {
+			break
+		}
+		h := queue[newest]
+		queue = append(queue[:newest], queue[newest+1:]...)
+		queued[h] = false
+		for _, p := range commits[h].parents {
+			push(p, flags[h])
+		}
+	}
+	for _, f := range flags {
+		switch f {
+		case fromLocal:
+			ahead++
+		case fromUpstream:
+			behind++
+		}
+	}
+	return ahead, behind
+}
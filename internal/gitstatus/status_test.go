@@ -0,0 +1,204 @@
+package gitstatus
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitRun runs git in dir for a test, with a fixed identity.
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=gsh", "GIT_AUTHOR_EMAIL=gsh@example.com",
+		"GIT_COMMITTER_NAME=gsh", "GIT_COMMITTER_EMAIL=gsh@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	base := t.TempDir()
+	origin := filepath.Join(base, "origin")
+	repo := filepath.Join(base, "repo")
+	os.Mkdir(origin, 0755)
+	gitRun(t, origin, "init", "-q", "-b", "main")
+	writeFile(t, filepath.Join(origin, "README"), strings.Repeat("line\n", 200))
+	gitRun(t, origin, "add", ".")
+	gitRun(t, origin, "commit", "-q", "-m", "first")
+	gitRun(t, base, "clone", "-q", "origin", "repo")
+
+	c := NewCache()
+	dir := filepath.Join(repo, "sub")
+	os.Mkdir(dir, 0755)
+	check := func(want string) {
+		t.Helper()
+		status, ok := c.Status(dir)
+		if got := status.String(); !ok && want != "" || ok && got != want {
+			t.Errorf("Status(%q) = %q, %v, want %q", dir, got, ok, want)
+		}
+	}
+	check("main u=")
+
+	// Untracked, ignored, staged and unstaged files
+	writeFile(t, filepath.Join(repo, ".gitignore"), "*.log\nbuild/\n!keep.log\n")
+	check("main % u=")
+	gitRun(t, repo, "add", ".gitignore")
+	check("main + u=")
+	writeFile(t, filepath.Join(repo, "sub", "debug.log"), "x")
+	writeFile(t, filepath.Join(repo, "build", "out"), "x")
+	check("main + u=")
+	writeFile(t, filepath.Join(repo, "sub", "keep.log"), "x")
+	check("main +% u=")
+	os.Remove(filepath.Join(repo, "sub", "keep.log"))
+	writeFile(t, filepath.Join(repo, "README"), "changed\n")
+	check("main *+ u=")
+
+	// Ahead of the upstream, and behind once it has a new commit, with
+	// the objects packed
+	gitRun(t, repo, "commit", "-q", "-am", "second")
+	check("main u+1")
+	writeFile(t, filepath.Join(origin, "README"), strings.Repeat("line\n", 200)+"more\n")
+	gitRun(t, origin, "commit", "-q", "-am", "upstream")
+	gitRun(t, repo, "fetch", "-q")
+	gitRun(t, repo, "gc", "-q", "--aggressive")
+	gitRun(t, repo, "update-index", "--index-version", "4")
+	check("main u+1-1")
+
+	// Touching a file without changing it isn't a change
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(repo, ".gitignore"), later, later)
+	check("main u+1-1")
+
+	gitRun(t, repo, "checkout", "-q", "--detach", "HEAD~1")
+	out, _ := exec.Command("git", "-C", repo, "rev-parse", "--short=7", "HEAD").Output()
+	// .gitignore is untracked again
+	check("(" + strings.TrimSpace(string(out)) + "...) %")
+
+	dir = base
+	check("")
+}
+
+func TestAheadBehind_Merges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// Commits made in the same second are visited nearest first, so a
+	// commit may be reached from the other side after it was visited
+	t.Setenv("GIT_AUTHOR_DATE", "2024-01-01T00:00:00Z")
+	t.Setenv("GIT_COMMITTER_DATE", "2024-01-01T00:00:00Z")
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q", "-b", "main")
+	commit := func(msg string) {
+		t.Helper()
+		writeFile(t, filepath.Join(dir, msg), msg)
+		gitRun(t, dir, "add", msg)
+		gitRun(t, dir, "commit", "-q", "-m", msg)
+	}
+	// The merged m1 is nearer main than topic, so it is visited from main
+	// first
+	commit("base")
+	gitRun(t, dir, "checkout", "-q", "-b", "topic")
+	commit("t1")
+	gitRun(t, dir, "checkout", "-q", "main")
+	commit("m1")
+	commit("m2")
+	commit("m3")
+	gitRun(t, dir, "checkout", "-q", "topic")
+	gitRun(t, dir, "merge", "-q", "--no-edit", "main~2")
+	commit("t2")
+	commit("t3")
+	commit("t4")
+	gitRun(t, dir, "config", "branch.topic.remote", ".")
+	gitRun(t, dir, "config", "branch.topic.merge", "refs/heads/main")
+
+	out, err := exec.Command("git", "-C", dir, "rev-list", "--left-right", "--count", "topic...main").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want Status
+	fmt.Sscan(string(out), &want.Ahead, &want.Behind)
+	status, _ := NewCache().Status(dir)
+	if status.Ahead != want.Ahead || status.Behind != want.Behind {
+		t.Errorf("ahead, behind = %d, %d, want %d, %d", status.Ahead, status.Behind, want.Ahead, want.Behind)
+	}
+}
+
+func TestStatus_BadIndex(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q", "-b", "main")
+	writeFile(t, filepath.Join(dir, "file"), "x")
+	c := NewCache()
+	if status, _ := c.Status(dir); status.String() != "main %" {
+		t.Errorf("status without an index = %q, want %q", status, "main %")
+	}
+	gitRun(t, dir, "add", "file")
+	gitRun(t, dir, "commit", "-q", "-m", "first")
+	writeFile(t, filepath.Join(dir, "other"), "x")
+	writeFile(t, filepath.Join(dir, ".git", "index"), "not an index")
+	// Unknown changes are shown as none
+	if status, _ := c.Status(dir); status.String() != "main" {
+		t.Errorf("status with a bad index = %q, want %q", status, "main")
+	}
+}
+
+func TestStatus_PacksKeptOpen(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q", "-b", "main")
+	writeFile(t, filepath.Join(dir, "file"), "x")
+	gitRun(t, dir, "add", "file")
+	gitRun(t, dir, "commit", "-q", "-m", "first")
+	gitRun(t, dir, "gc", "-q")
+
+	c := NewCache()
+	c.Status(dir)
+	g := c.repos[c.dirs[dir]].repo
+	if len(g.packs) != 1 {
+		t.Fatalf("%d packs open, want 1", len(g.packs))
+	}
+	p := g.packs[0]
+	_, commit, _ := g.head()
+	for range 3 {
+		if _, _, err := g.readObject(commit); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(g.packs) != 1 || g.packs[0] != p {
+		t.Errorf("pack opened again when reading from it")
+	}
+
+	// A new commit may come with new packs, so they are opened again
+	writeFile(t, filepath.Join(dir, "file"), "y")
+	gitRun(t, dir, "commit", "-q", "-am", "second")
+	gitRun(t, dir, "gc", "-q")
+	c.Status(dir)
+	if _, err := p.file.Stat(); err == nil {
+		t.Errorf("old pack left open after the repository changed")
+	}
+}
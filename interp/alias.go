@@ -0,0 +1,93 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+func init() {
+	defaultBuiltins["alias"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		operands := c.Args[1:]
+		if len(operands) > 0 && operands[0] == "-p" {
+			operands = operands[1:]
+		}
+		if len(operands) == 0 {
+			for _, name := range r.Aliases() {
+				fmt.Fprintf(c.Stdout, "alias %s=%s\n", name, shellQuote(r.aliases[name]))
+			}
+			return 0
+		}
+		status := 0
+		for _, arg := range operands {
+			name, value, hasValue := strings.Cut(arg, "=")
+			switch {
+			case !validAliasName(name):
+				fmt.Fprintf(c.Stderr, "alias: `%s': invalid alias name\n", name)
+				status = 1
+			case hasValue:
+				r.aliases[name] = value
+			default:
+				if value, ok := r.aliases[name]; ok {
+					fmt.Fprintf(c.Stdout, "alias %s=%s\n", name, shellQuote(value))
+				} else {
+					fmt.Fprintf(c.Stderr, "alias: %s: not found\n", name)
+					status = 1
+				}
+			}
+		}
+		return status
+	})
+	defaultBuiltins["unalias"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		if len(c.Args) > 1 && c.Args[1] == "-a" {
+			clear(r.aliases)
+			return 0
+		}
+		if len(c.Args) == 1 {
+			fmt.Fprintln(c.Stderr, "unalias: usage: unalias [-a] name [name ...]")
+			return 2
+		}
+		status := 0
+		for _, name := range c.Args[1:] {
+			if _, ok := r.aliases[name]; !ok {
+				fmt.Fprintf(c.Stderr, "unalias: %s: not found\n", name)
+				status = 1
+				continue
+			}
+			delete(r.aliases, name)
+		}
+		return status
+	})
+}
+
+// validAliasName reports whether name can be defined as an alias: a non-empty
+// word without quotes, expansions, slashes or characters that end a word.
+func validAliasName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n;&|()<>'\"\\$`/=")
+}
+
+// Alias returns the value of the alias name.
+func (r *Runner) Alias(name string) (string, bool) {
+	value, ok := r.aliases[name]
+	return value, ok
+}
+
+// Aliases returns the names of the defined aliases, sorted.
+func (r *Runner) Aliases() []string {
+	names := make([]string, 0, len(r.aliases))
+	for name := range r.aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parse parses src, expanding the aliases defined so far.
+func (r *Runner) parse(src string) (*syntax.List, error) {
+	p := syntax.Parser{Aliases: r.Alias}
+	return p.Parse(src)
+}
@@ -0,0 +1,73 @@
+package interp
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runLines runs src with RunReader, one complete command at a time, as a
+// script would be, and returns its output.
+func runLines(t *testing.T, src string) string {
+	t.Helper()
+	r := New()
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r.Stdout, r.Stderr = f, f
+	r.RunReader(context.Background(), strings.NewReader(src))
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	return string(got)
+}
+
+func TestAliases(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"alias say='echo said'\nsay hi\n", "said hi\n"},
+		{"alias a='echo a'\nalias b=a\nb x\n", "a x\n"},
+		{"alias echo='echo loop'\necho x\n", "loop x\n"},
+		{"alias a=b b=a\na 2>/dev/null; echo $?\n", "127\n"},
+		{"alias run='env ' hi='echo hi'\nrun hi\n", "hi\n"},
+		{"alias run='env ' hi='echo hi'\nrun echo hi\n", "hi\n"},
+		{"alias hi='echo hi'\n'hi' 2>/dev/null || echo quoted\n", "quoted\n"},
+		{"alias hi='echo hi'; hi 2>/dev/null || echo same line\n", "same line\n"},
+		{"alias up='tr a-z A-Z'\necho abc | up\n", "ABC\n"},
+		{"alias hi='echo hi'\nunalias hi\nhi 2>/dev/null || echo gone\n", "gone\n"},
+		{"alias a=x b=y\nunalias -a\nalias\n", ""},
+		{"alias b='echo b' a='echo it'\\''s'\nalias\nalias -p b\n", "alias a='echo it'\\''s'\nalias b='echo b'\nalias b='echo b'\n"},
+		{"alias ll='ls -l'\ntype ll\n", "ll is aliased to `ls -l'\n"},
+		{"alias nope\n", "alias: nope: not found\n"},
+		{"alias 'a b=c'\necho $?\n", "alias: `a b': invalid alias name\n1\n"},
+		{"unalias nope\necho $?\n", "unalias: nope: not found\n1\n"},
+		{"alias hi='echo hi'\n(hi)\nf() { hi; }\nf\n", "hi\nhi\n"},
+	}
+	for _, tt := range tests {
+		if got := runLines(t, tt.src); got != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestAliases_API(t *testing.T) {
+	r := New()
+	runIn(t, r, "alias b=2 a=1")
+	if v, ok := r.Alias("a"); !ok || v != "1" {
+		t.Errorf("Alias(a) = %q, %v, want 1, true", v, ok)
+	}
+	if got := strings.Join(r.Aliases(), " "); got != "a b" {
+		t.Errorf("Aliases() = %q, want %q", got, "a b")
+	}
+	sub := r.subshell()
+	sub.aliases["c"] = "3"
+	if _, ok := r.Alias("c"); ok {
+		t.Errorf("alias defined in a subshell leaked into its parent")
+	}
+}
@@ -0,0 +1,423 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// arithOps lists the operators of arithmetic expressions, longest first so
+// they are matched greedily.
+var arithOps = []string{
+	"<<=", ">>=",
+	"**", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+=", "-=", "*=", "/=", "%=", "&=", "^=", "|=",
+	"+", "-", "*", "/", "%", "<", ">", "=", "!", "~", "&", "^", "|", "?", ":", "(", ")", ",",
+}
+
+// arithToken is a number, a variable name or an operator.
+type arithToken struct {
+	val   string
+	isNum bool
+	num   int64
+	isVar bool
+}
+
+// arith evaluates an expression while parsing it. skip is non-zero inside
+// operands that short-circuiting or ?: leaves unevaluated, where assignments
+// and division by zero must have no effect.
+type arith struct {
+	r    *Runner
+	toks []arithToken
+	pos  int
+	skip int
+}
+
+// evalArith evaluates a shell arithmetic expression such as i<10 or i+=2,
+// reading and assigning shell variables. An empty expression evaluates to 0.
+func (r *Runner) evalArith(expr string) (int64, error) {
+	toks, err := lexArith(expr)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", expr, err)
+	}
+	if len(toks) == 0 {
+		return 0, nil
+	}
+	a := &arith{r: r, toks: toks}
+	v, err := a.comma()
+	if err == nil && a.pos < len(a.toks) {
+		err = fmt.Errorf("syntax error in expression (error token is \"%s\")", a.toks[a.pos].val)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", expr, err)
+	}
+	return v, nil
+}
+
+func lexArith(expr string) ([]arithToken, error) {
+	var toks []arithToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case isDigit(c):
+			j := i
+			for j < len(expr) && (isDigit(expr[j]) || isAlpha(expr[j])) {
+				j++
+			}
+			n, err := strconv.ParseInt(expr[i:j], 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value too great for base (error token is \"%s\")", expr[i:j])
+			}
+			toks = append(toks, arithToken{val: expr[i:j], isNum: true, num: n})
+			i = j
+		case c == '$':
+			// $name and ${name} are read the same way as a bare name
+			name, n := scanParamName(expr[i+1:])
+			if n == 0 {
+				return nil, fmt.Errorf("syntax error: operand expected (error token is \"%s\")", expr[i:])
+			}
+			toks = append(toks, arithToken{val: name, isVar: true})
+			i += n + 1
+		case c == '_' || isAlpha(c):
+			name, n := scanParamName(expr[i:])
+			toks = append(toks, arithToken{val: name, isVar: true})
+			i += n
+		default:
+			op := matchPrefix(expr[i:], arithOps)
+			if op == "" {
+				return nil, fmt.Errorf("syntax error: invalid arithmetic operator (error token is \"%s\")", expr[i:])
+			}
+			toks = append(toks, arithToken{val: op})
+			i += len(op)
+		}
+	}
+	return toks, nil
+}
+
+func (a *arith) peek() string {
+	if a.pos < len(a.toks) && !a.toks[a.pos].isNum && !a.toks[a.pos].isVar {
+		return a.toks[a.pos].val
+	}
+	return ""
+}
+
+func (a *arith) comma() (int64, error) {
+	v, err := a.assign()
+	for err == nil && a.peek() == "," {
+		a.pos++
+		v, err = a.assign()
+	}
+	return v, err
+}
+
+// assign handles name = expr and the compound assignment operators, which
+// bind right to left.
+func (a *arith) assign() (int64, error) {
+	if a.pos+1 < len(a.toks) && a.toks[a.pos].isVar {
+		op := a.toks[a.pos+1].val
+		if !a.toks[a.pos+1].isNum && !a.toks[a.pos+1].isVar && strings.HasSuffix(op, "=") &&
+			op != "==" && op != "!=" && op != "<=" && op != ">=" {
+			name := a.toks[a.pos].val
+			a.pos += 2
+			v, err := a.assign()
+			if err != nil {
+				return 0, err
+			}
+			if op != "=" {
+				cur, err := a.variable(name)
+				if err != nil {
+					return 0, err
+				}
+				if v, err = a.binary(strings.TrimSuffix(op, "="), cur, v); err != nil {
+					return 0, err
+				}
+			}
+			a.setVariable(name, v)
+			return v, nil
+		}
+	}
+	return a.ternary()
+}
+
+func (a *arith) ternary() (int64, error) {
+	cond, err := a.binaryLevel(0)
+	if err != nil || a.peek() != "?" {
+		return cond, err
+	}
+	a.pos++
+	if cond == 0 {
+		a.skip++
+	}
+	yes, err := a.assign()
+	if cond == 0 {
+		a.skip--
+	}
+	if err != nil {
+		return 0, err
+	}
+	if a.peek() != ":" {
+		return 0, fmt.Errorf("`:' expected for conditional expression")
+	}
+	a.pos++
+	if cond != 0 {
+		a.skip++
+	}
+	no, err := a.assign()
+	if cond != 0 {
+		a.skip--
+	}
+	if cond != 0 {
+		return yes, err
+	}
+	return no, err
+}
+
+// binaryLevels lists the left-associative binary operators from the loosest
+// to the tightest binding.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (a *arith) binaryLevel(level int) (int64, error) {
+	if level == len(binaryLevels) {
+		return a.power()
+	}
+	v, err := a.binaryLevel(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := a.peek()
+		if !containsString(binaryLevels[level], op) {
+			return v, nil
+		}
+		a.pos++
+		// The right operand of && and || is only evaluated when it matters
+		shortCircuit := (op == "&&" && v == 0) || (op == "||" && v != 0)
+		if shortCircuit {
+			a.skip++
+		}
+		rhs, err := a.binaryLevel(level + 1)
+		if shortCircuit {
+			a.skip--
+		}
+		if err != nil {
+			return 0, err
+		}
+		if v, err = a.binary(op, v, rhs); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (a *arith) power() (int64, error) {
+	base, err := a.unary()
+	if err != nil || a.peek() != "**" {
+		return base, err
+	}
+	a.pos++
+	exp, err := a.power()
+	if err != nil {
+		return 0, err
+	}
+	return a.binary("**", base, exp)
+}
+
+func (a *arith) binary(op string, x, y int64) (int64, error) {
+	b := func(c bool) int64 {
+		if c {
+			return 1
+		}
+		return 0
+	}
+	switch op {
+	case "||":
+		return b(x != 0 || y != 0), nil
+	case "&&":
+		return b(x != 0 && y != 0), nil
+	case "|":
+		return x | y, nil
+	case "^":
+		return x ^ y, nil
+	case "&":
+		return x & y, nil
+	case "==":
+		return b(x == y), nil
+	case "!=":
+		return b(x != y), nil
+	case "<":
+		return b(x < y), nil
+	case "<=":
+		return b(x <= y), nil
+	case ">":
+		return b(x > y), nil
+	case ">=":
+		return b(x >= y), nil
+	case "<<":
+		return x << uint64(y), nil
+	case ">>":
+		return x >> uint64(y), nil
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/", "%":
+		if y == 0 {
+			if a.skip > 0 {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("division by 0")
+		}
+		if op == "/" {
+			return x / y, nil
+		}
+		return x % y, nil
+	case "**":
+		if y < 0 {
+			return 0, fmt.Errorf("exponent less than 0")
+		}
+		r := int64(1)
+		for ; y > 0; y-- {
+			r *= x
+		}
+		return r, nil
+	}
+	return 0, fmt.Errorf("unknown operator %s", op)
+}
+
+func (a *arith) unary() (int64, error) {
+	switch op := a.peek(); op {
+	case "!", "~", "+", "-":
+		a.pos++
+		v, err := a.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "!":
+			if v == 0 {
+				return 1, nil
+			}
+			return 0, nil
+		case "~":
+			return ^v, nil
+		case "-":
+			return -v, nil
+		}
+		return v, nil
+	case "++", "--":
+		a.pos++
+		if a.pos >= len(a.toks) || !a.toks[a.pos].isVar {
+			return 0, fmt.Errorf("%s: variable name expected", op)
+		}
+		name := a.toks[a.pos].val
+		a.pos++
+		v, err := a.variable(name)
+		if err != nil {
+			return 0, err
+		}
+		if op == "++" {
+			v++
+		} else {
+			v--
+		}
+		a.setVariable(name, v)
+		return v, nil
+	}
+	return a.postfix()
+}
+
+func (a *arith) postfix() (int64, error) {
+	if a.pos >= len(a.toks) {
+		return 0, fmt.Errorf("syntax error: operand expected")
+	}
+	t := a.toks[a.pos]
+	switch {
+	case t.isNum:
+		a.pos++
+		return t.num, nil
+	case t.isVar:
+		a.pos++
+		v, err := a.variable(t.val)
+		if err != nil {
+			return 0, err
+		}
+		if op := a.peek(); op == "++" || op == "--" {
+			a.pos++
+			if op == "++" {
+				a.setVariable(t.val, v+1)
+			} else {
+				a.setVariable(t.val, v-1)
+			}
+		}
+		return v, nil
+	case t.val == "(":
+		a.pos++
+		v, err := a.comma()
+		if err != nil {
+			return 0, err
+		}
+		if a.peek() != ")" {
+			return 0, fmt.Errorf("missing `)'")
+		}
+		a.pos++
+		return v, nil
+	}
+	return 0, fmt.Errorf("syntax error: operand expected (error token is \"%s\")", t.val)
+}
+
+// variable returns the integer value of a shell variable. Unset and empty
+// variables are 0.
+func (a *arith) variable(name string) (int64, error) {
+	value, _ := a.r.lookupParam(name)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		if a.skip > 0 {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%s: invalid number (value of %s)", value, name)
+	}
+	return n, nil
+}
+
+func (a *arith) setVariable(name string, v int64) {
+	if a.skip == 0 {
+		a.r.setVar(name, strconv.FormatInt(v, 10))
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPrefix returns the first of ops that s starts with, or "".
+func matchPrefix(s string, ops []string) string {
+	for _, op := range ops {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
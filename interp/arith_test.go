@@ -0,0 +1,50 @@
+package interp
+
+import "testing"
+
+func TestEvalArith(t *testing.T) {
+	r := New()
+
+	tests := []struct {
+		expr string
+		want int64
+	}{
+		{"", 0},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"2 ** 3 ** 2", 512},
+		{"-7 / 2", -3},
+		{"7 % 3", 1},
+		{"1 < 2 && 2 <= 2", 1},
+		{"0x10 + 010", 24},
+		{"x = 5", 5},
+		{"x += 2", 7},
+		{"x++", 7},
+		{"++x", 9},
+		{"$x - 1", 8},
+		{"x > 5 ? 1 : 2", 1},
+		{"0 && (x = 100)", 0},
+		{"x", 9},
+		{"!x, ~0", -1},
+		{"1 << 4 | 1", 17},
+	}
+	for _, tt := range tests {
+		got, err := r.evalArith(tt.expr)
+		if err != nil {
+			t.Errorf("r.evalArith(%q) returned error: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("r.evalArith(%q) = %d, want %d", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalArith_Errors(t *testing.T) {
+	r := New()
+	for _, expr := range []string{"1 / 0", "1 +", "(1", "2 @ 3", "1 2"} {
+		if _, err := r.evalArith(expr); err == nil {
+			t.Errorf("r.evalArith(%q) should return an error", expr)
+		}
+	}
+}
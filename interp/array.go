@@ -0,0 +1,164 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Indexed arrays are kept apart from the string variables, in r.arrays. An
+// array's name on its own refers to its first element, as in bash.
+
+// arrayRef splits a parameter name of the form name[subscript].
+func arrayRef(name string) (base, sub string, ok bool) {
+	open := strings.IndexByte(name, '[')
+	if open <= 0 || !strings.HasSuffix(name, "]") {
+		return "", "", false
+	}
+	return name[:open], name[open+1 : len(name)-1], true
+}
+
+// arrayWords returns the elements name[@] or name[*] refers to, and whether
+// name has that form. A string variable counts as an array of one element.
+func (r *Runner) arrayWords(name string) ([]string, bool) {
+	base, sub, ok := arrayRef(name)
+	if !ok || sub != "@" && sub != "*" {
+		return nil, false
+	}
+	if arr, ok := r.arrays[base]; ok {
+		return arr, true
+	}
+	if value, ok := r.lookupParam(base); ok {
+		return []string{value}, true
+	}
+	return nil, true
+}
+
+// lookupArrayParam looks up the forms of parameter that concern arrays and
+// lengths: name[subscript] and #name, which gives the length of a value or
+// the number of elements of name[@]. It reports false in handled if name is
+// none of these.
+func (r *Runner) lookupArrayParam(name string) (value string, set, handled bool) {
+	if rest, ok := strings.CutPrefix(name, "#"); ok && rest != "" {
+		if words, ok := r.arrayWords(rest); ok {
+			return strconv.Itoa(len(words)), true, true
+		}
+		v, _ := r.lookupParam(rest)
+		return strconv.Itoa(utf8.RuneCountInString(v)), true, true
+	}
+	base, sub, ok := arrayRef(name)
+	if !ok {
+		return "", false, false
+	}
+	if words, ok := r.arrayWords(name); ok {
+		return strings.Join(words, " "), len(words) > 0, true
+	}
+	arr, isArray := r.arrays[base]
+	i, err := r.arrayIndex(sub, len(arr))
+	if err != nil {
+		return "", false, true
+	}
+	if !isArray {
+		// A string variable is element 0
+		if i != 0 {
+			return "", false, true
+		}
+		value, set := r.lookupParam(base)
+		return value, set, true
+	}
+	if i < 0 || i >= len(arr) {
+		return "", false, true
+	}
+	return arr[i], true, true
+}
+
+// arrayIndex evaluates a subscript, which is an arithmetic expression. A
+// negative index counts back from the end of an array of length n.
+func (r *Runner) arrayIndex(sub string, n int) (int, error) {
+	fields, err := r.expandWord(sub, false)
+	if err != nil {
+		return 0, err
+	}
+	v, err := r.evalArith(strings.Join(fields, ""))
+	if err != nil {
+		return 0, err
+	}
+	i := int(v)
+	if i < 0 {
+		i += n
+	}
+	return i, nil
+}
+
+// setArray makes name an array holding values, replacing any variable of
+// that name.
+func (r *Runner) setArray(name string, values []string) {
+	delete(r.vars, name)
+	delete(r.env, name)
+	r.arrays[name] = values
+}
+
+// setArrayElement assigns element i of the array name, making the array
+// longer if needed. A string variable becomes the first element of a new
+// array.
+func (r *Runner) setArrayElement(name string, i int, value string) {
+	arr, ok := r.arrays[name]
+	if !ok {
+		if v, set := r.lookupParam(name); set {
+			arr = []string{v}
+		}
+	}
+	// Copy, as a subshell's arrays share their elements with the shell's
+	arr = append([]string(nil), arr...)
+	for len(arr) <= i {
+		arr = append(arr, "")
+	}
+	arr[i] = value
+	r.setArray(name, arr)
+}
+
+// assign performs the assignment word name=raw, where name may end in + to
+// append, or have a subscript, and raw may be a parenthesised list of words
+// for an array.
+func (r *Runner) assign(name, raw string) error {
+	name, appending := strings.CutSuffix(name, "+")
+	if strings.HasPrefix(raw, "(") && strings.HasSuffix(raw, ")") {
+		words, err := r.expandWord(raw[1:len(raw)-1], true)
+		if err != nil {
+			return err
+		}
+		if appending {
+			old, _ := r.arrayWords(name + "[@]")
+			words = append(append([]string(nil), old...), words...)
+		}
+		r.setArray(name, words)
+		return nil
+	}
+	fields, err := r.expandWord(raw, false)
+	if err != nil {
+		return err
+	}
+	value := strings.Join(fields, "")
+	if base, sub, ok := arrayRef(name); ok {
+		i, err := r.arrayIndex(sub, len(r.arrays[base]))
+		if err != nil {
+			return err
+		}
+		if i < 0 {
+			return fmt.Errorf("%s: bad array subscript", name)
+		}
+		if appending {
+			old, _, _ := r.lookupArrayParam(name)
+			value = old + value
+		}
+		r.setArrayElement(base, i, value)
+		return nil
+	}
+	if appending {
+		old, _ := r.lookupParam(name)
+		value = old + value
+	}
+	r.setVar(name, value)
+	return nil
+}
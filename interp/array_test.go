@@ -0,0 +1,48 @@
+package interp
+
+import "testing"
+
+func TestArrays(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`a=(x y z); echo ${a[0]} ${a[2]} ${a[-1]} ${#a[@]}`, "x z z 3\n"},
+		{`a=(x 'y z'); for w in "${a[@]}"; do echo "<$w>"; done`, "<x>\n<y z>\n"},
+		{`a=(x y); echo "${a[*]}"; echo $a`, "x y\nx\n"},
+		{`a=(x); a+=(y z); echo ${a[@]}`, "x y z\n"},
+		{`a=(x); a[3]=w; echo ${#a[@]} "${a[3]}"`, "4 w\n"},
+		{`a=(x y); a=z; echo ${a[@]}`, "z y\n"},
+		{`i=1; a=(x y); echo ${a[i]} ${a[$i-1]}`, "y x\n"},
+		{`s=str; s+=(more); echo ${s[@]}`, "str more\n"},
+		{"a=(one # a comment\n  two\n)\necho ${a[@]}", "one two\n"},
+		{`echo ${#a[@]} ${#nothing}`, "0 0\n"},
+		{`n=hello; echo ${#n}`, "5\n"},
+		{`a=( $(echo p q) ); echo ${#a[@]}`, "2\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCommandSubstitution(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`echo $(echo a b)`, "a b\n"},
+		{`echo "$(echo a; echo b)"`, "a\nb\n"},
+		{"echo `echo back`", "back\n"},
+		{`x=$(echo "(paren)"); echo $x`, "(paren)\n"},
+		{`echo $(echo $(echo nested))`, "nested\n"},
+		{`echo $((2 * 3 + 1))`, "7\n"},
+		{`f() { echo fn; }; echo "got $(f)"`, "got fn\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
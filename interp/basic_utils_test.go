@@ -0,0 +1,63 @@
+package interp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandWord(t *testing.T) {
+	r := New()
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"foo bar", []string{"foo", "bar"}},
+		{"'foo bar'", []string{"foo bar"}},
+		{"foo 'bar baz'", []string{"foo", "bar baz"}},
+		{"foo \"bar baz\"", []string{"foo", "bar baz"}},
+		{"foo\\ bar baz", []string{"foo bar", "baz"}},
+		{"foo 'bar baz' qux", []string{"foo", "bar baz", "qux"}},
+	}
+	for _, tt := range tests {
+		got, err := r.expandWord(tt.input, true)
+		if err != nil {
+			t.Errorf("r.expandWord(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("r.expandWord(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpandWord_Parameters(t *testing.T) {
+	r := New()
+	r.Params = []string{"a b", "c"}
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"echo $1", []string{"echo", "a", "b"}},
+		{"echo \"$1\"", []string{"echo", "a b"}},
+		{"echo ${2}x", []string{"echo", "cx"}},
+		{"echo $#", []string{"echo", "2"}},
+		{"echo \"$*\"", []string{"echo", "a b c"}},
+		{"echo \"<$@>\"", []string{"echo", "<a b", "c>"}},
+		{"echo '$1'", []string{"echo", "$1"}},
+		{"echo \\$1", []string{"echo", "$1"}},
+		{"echo $3", []string{"echo"}},
+		{"echo a#b # comment", []string{"echo", "a#b"}},
+		{"# only a comment", nil},
+	}
+	for _, tt := range tests {
+		got, err := r.expandWord(tt.input, true)
+		if err != nil {
+			t.Errorf("r.expandWord(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("r.expandWord(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
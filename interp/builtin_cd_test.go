@@ -1,4 +1,4 @@
-package main
+package interp
 
 import (
 	"bytes"
@@ -8,82 +8,72 @@ import (
 )
 
 func TestBuiltinCd_ValidDir(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
-	origDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("could not get current dir: %v", err)
-	}
 	tmpDir := os.TempDir()
 
 	args := []string{"cd", tmpDir}
-	err = builtins["cd"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err != nil {
 		t.Fatalf("cd returned error: %v", err)
 	}
 
 	// Check that we actually changed directory
-	got, _ := os.Getwd()
-	if got != tmpDir {
-		t.Errorf("cd did not change directory: got %q, want %q", got, tmpDir)
+	if r.Dir != tmpDir {
+		t.Errorf("cd did not change directory: got %q, want %q", r.Dir, tmpDir)
 	}
-
-	// Restore original directory
-	os.Chdir(origDir)
 }
 
 func TestBuiltinCd_InvalidDir(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
-	origDir, _ := os.Getwd()
+	origDir := r.Dir
 	badDir := filepath.Join(os.TempDir(), "notarealdir")
 
 	args := []string{"cd", badDir}
-	err := builtins["cd"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err == nil {
 		t.Errorf("cd should return error for invalid dir")
 	}
 
 	// Should not have changed directory
-	got, _ := os.Getwd()
-	if got != origDir {
-		t.Errorf("cd changed directory on error: got %q, want %q", got, origDir)
+	if r.Dir != origDir {
+		t.Errorf("cd changed directory on error: got %q, want %q", r.Dir, origDir)
 	}
 }
 
 func TestBuiltinCd_HomeShortcut(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
-	origDir, _ := os.Getwd()
 	home, err := os.UserHomeDir()
 	if err != nil {
 		t.Skip("cannot determine home directory")
 	}
 
 	args := []string{"cd", "~"}
-	err = builtins["cd"](args, &out, &errOut, nil)
+	err = callBuiltin(r, args, &out, &errOut, nil)
 	if err != nil {
 		t.Fatalf("cd returned error: %v", err)
 	}
 
-	got, _ := os.Getwd()
-	if got != home {
-		t.Errorf("cd ~ did not change to home: got %q, want %q", got, home)
+	if r.Dir != home {
+		t.Errorf("cd ~ did not change to home: got %q, want %q", r.Dir, home)
 	}
-
-	// Restore original directory
-	os.Chdir(origDir)
 }
 
 func TestBuiltinCd_TooManyArgs(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
 	args := []string{"cd", "a", "b"}
-	err := builtins["cd"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err == nil {
 		t.Errorf("cd should return error for too many arguments")
 	}
@@ -92,3 +82,21 @@ func TestBuiltinCd_TooManyArgs(t *testing.T) {
 		t.Errorf("cd stderr = %q, want %q", errOut.String(), want)
 	}
 }
+
+func TestBuiltinCd_KeepsProcessDir(t *testing.T) {
+	r := New()
+	procDir, _ := os.Getwd()
+	dir := t.TempDir()
+
+	if err := callBuiltin(r, []string{"cd", dir}, &bytes.Buffer{}, &bytes.Buffer{}, nil); err != nil {
+		t.Fatalf("cd returned error: %v", err)
+	}
+	if got, _ := os.Getwd(); got != procDir {
+		t.Errorf("cd changed the process directory to %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, "f.txt"), []byte("x"), 0644)
+	got := runIn(t, r, "pwd; cat f.txt; echo; echo *.txt; echo y > g.txt; cd ..; cat "+filepath.Base(dir)+"/g.txt")
+	if want := dir + "\nx\nf.txt\ny\n"; got != want {
+		t.Errorf("commands after cd printed %q, want %q", got, want)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package interp
 
 import (
 	"bytes"
@@ -6,12 +6,13 @@ import (
 )
 
 func TestBuiltinEcho(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
 	// Simulate: echo hello world
 	args := []string{"echo", "hello", "world"}
-	err := builtins["echo"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err != nil {
 		t.Fatalf("echo returned error: %v", err)
 	}
@@ -28,12 +29,13 @@ func TestBuiltinEcho(t *testing.T) {
 }
 
 func TestBuiltinEcho_Empty(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
 	// Simulate: echo
 	args := []string{"echo"}
-	err := builtins["echo"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err != nil {
 		t.Fatalf("echo returned error: %v", err)
 	}
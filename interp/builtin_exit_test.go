@@ -0,0 +1,33 @@
+package interp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuiltinExit(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	err := callBuiltin(r, []string{"exit", "3"}, &out, &errOut, nil)
+	if exitStatus(err) != 3 {
+		t.Errorf("exit returned %v, want status 3", err)
+	}
+	if !r.Exited() {
+		t.Fatalf("exit did not mark the shell as exited")
+	}
+	if status := r.Exit(); status != 3 {
+		t.Errorf("Exit() = %d, want 3", status)
+	}
+}
+
+func TestBuiltinExit_StopsCommands(t *testing.T) {
+	r := New()
+	if got := runIn(t, r, "echo a; false; for i in 1 2; do exit; done; echo b"); got != "a\n" {
+		t.Errorf("output = %q, want %q", got, "a\n")
+	}
+	if status := r.Exit(); status != 1 {
+		t.Errorf("Exit() = %d, want the last status 1", status)
+	}
+}
@@ -0,0 +1,156 @@
+package interp
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltinKill_ListTranslates(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	// Simulate: kill -l 15 130 TERM SIGINT
+	err := callBuiltin(r, []string{"kill", "-l", "15", "130", "TERM", "SIGINT"}, &out, &errOut, nil)
+	if err != nil {
+		t.Fatalf("kill -l returned error: %v", err)
+	}
+	want := "TERM\nINT\n15\n2\n"
+	if out.String() != want {
+		t.Errorf("kill -l output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestBuiltinKill_ListAll(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	callBuiltin(r, []string{"kill", "-l"}, &out, &errOut, nil)
+	if !strings.HasPrefix(out.String(), " 1) SIGHUP\t 2) SIGINT") {
+		t.Errorf("kill -l output = %q, want signal table", out.String())
+	}
+}
+
+func TestBuiltinKill_JobSpec(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not found in PATH")
+	}
+	j, err := r.startJob(r.newShellCmd([]string{"sleep", "10"}), "sleep 10", nil)
+	if err != nil {
+		t.Fatalf("startJob returned error: %v", err)
+	}
+
+	// Simulate: kill -s KILL %sleep
+	err = callBuiltin(r, []string{"kill", "-s", "KILL", "%sleep"}, &out, &errOut, nil)
+	if err != nil {
+		t.Fatalf("kill returned error: %v (stderr %q)", err, errOut.String())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r.jobsMu.Lock()
+		done, status := j.done, j.status
+		r.jobsMu.Unlock()
+		if done {
+			if status != 128+9 {
+				t.Errorf("job status = %d, want %d", status, 128+9)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job was not killed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	out.Reset()
+	r.reportDoneJobs(&out)
+	if !strings.Contains(out.String(), "Exit 137") {
+		t.Errorf("job report = %q, want it to mention %q", out.String(), "Exit 137")
+	}
+}
+
+func TestBuiltinKill_CompoundJobs(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not found in PATH")
+	}
+	for _, src := range []string{"sleep 10 | sleep 10 &", "{ sleep 10; sleep 10; } &", "while true; do sleep 10; done &"} {
+		r := New()
+		runIn(t, r, src)
+		// Wait for the job's first process to start
+		j, err := r.findJob("%1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for j.pgid() == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		var out, errOut bytes.Buffer
+		if err := callBuiltin(r, []string{"kill", "%1"}, &out, &errOut, nil); err != nil {
+			t.Errorf("%s: kill %%1 returned error: %v (stderr %q)", src, err, errOut.String())
+			continue
+		}
+		for {
+			r.jobsMu.Lock()
+			done := j.done
+			r.jobsMu.Unlock()
+			if done {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: job was not killed", src)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestBuiltinKill_NoSuchProcess(t *testing.T) {
+	r := New()
+	var out, errOut bytes.Buffer
+	// The highest PID Linux allows is 2^22
+	callBuiltin(r, []string{"kill", "4194305"}, &out, &errOut, nil)
+	want := "kill: (4194305) - No such process\n"
+	if errOut.String() != want {
+		t.Errorf("kill stderr = %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestBuiltinKill_NoSuchJob(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	err := callBuiltin(r, []string{"kill", "%99"}, &out, &errOut, nil)
+	if err == nil {
+		t.Errorf("kill should return error for an unknown job")
+	}
+	want := "kill: %99: no such job\n"
+	if errOut.String() != want {
+		t.Errorf("kill stderr = %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestBuiltinKill_InvalidSignal(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	err := callBuiltin(r, []string{"kill", "-NOPE", "1"}, &out, &errOut, nil)
+	if err == nil {
+		t.Errorf("kill should return error for an invalid signal")
+	}
+	want := "kill: NOPE: invalid signal specification\n"
+	if errOut.String() != want {
+		t.Errorf("kill stderr = %q, want %q", errOut.String(), want)
+	}
+}
@@ -1,26 +1,25 @@
-package main
+package interp
 
 import (
 	"bytes"
-	"os"
 	"strings"
 	"testing"
 )
 
 func TestBuiltinPwd(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
 	// Simulate: pwd
 	args := []string{"pwd"}
-	err := builtins["pwd"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err != nil {
 		t.Fatalf("pwd returned error: %v", err)
 	}
 
 	got := strings.TrimSpace(out.String())
-	want, _ := os.Getwd()
-	if got != want {
+	if want := r.Dir; got != want {
 		t.Errorf("pwd output = %q, want %q", got, want)
 	}
 
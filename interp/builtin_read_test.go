@@ -0,0 +1,94 @@
+package interp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinRead_SplitsFields(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	// Simulate: read first rest <<< "  one two  three  "
+	in := strings.NewReader("  one two  three  \nnext line\n")
+	err := callBuiltin(r, []string{"read", "first", "rest"}, &out, &errOut, in)
+	if err != nil {
+		t.Fatalf("read returned error: %v", err)
+	}
+	if r.vars["first"] != "one" || r.vars["rest"] != "two  three" {
+		t.Errorf("first, rest = %q, %q, want %q, %q", r.vars["first"], r.vars["rest"], "one", "two  three")
+	}
+
+	// The rest of the input must be left unread
+	remaining, _ := io.ReadAll(in)
+	if string(remaining) != "next line\n" {
+		t.Errorf("remaining input = %q, want %q", remaining, "next line\n")
+	}
+}
+
+func TestBuiltinRead_Backslashes(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	callBuiltin(r, []string{"read"}, &out, &errOut, strings.NewReader("a\\ b\\\nc\n"))
+	if r.vars["REPLY"] != "a bc" {
+		t.Errorf("REPLY = %q, want %q", r.vars["REPLY"], "a bc")
+	}
+
+	callBuiltin(r, []string{"read", "-r"}, &out, &errOut, strings.NewReader("a\\ b\n"))
+	if r.vars["REPLY"] != "a\\ b" {
+		t.Errorf("REPLY with -r = %q, want %q", r.vars["REPLY"], "a\\ b")
+	}
+}
+
+func TestBuiltinRead_EOF(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	err := callBuiltin(r, []string{"read"}, &out, &errOut, strings.NewReader("partial"))
+	if err == nil {
+		t.Errorf("read should return error at end of input")
+	}
+	if r.vars["REPLY"] != "partial" {
+		t.Errorf("REPLY = %q, want %q", r.vars["REPLY"], "partial")
+	}
+}
+
+func TestBuiltinRead_IFS(t *testing.T) {
+	tests := []struct {
+		ifs   string // "unset" leaves IFS unset
+		input string
+		want  []string
+	}{
+		{"unset", " x\ty  z \n", []string{"x", "y  z"}},
+		{":", "x:y\n", []string{"x", "y"}},
+		{":", "x::y:z\n", []string{"x", "", "y:z"}},
+		{" :", "  x : y  z  \n", []string{"x", "y", "z"}},
+		{"", "  x y  \n", []string{"  x y  ", ""}},
+		{":", "x\n", []string{"x", ""}},
+	}
+	for _, tt := range tests {
+		r := New()
+		if tt.ifs != "unset" {
+			r.vars["IFS"] = tt.ifs
+		}
+		names := []string{"a", "b", "c"}[:len(tt.want)]
+		callBuiltin(r, append([]string{"read"}, names...), &bytes.Buffer{}, &bytes.Buffer{}, strings.NewReader(tt.input))
+		for i, name := range names {
+			if r.vars[name] != tt.want[i] {
+				t.Errorf("IFS=%q read %v <<< %q: %s = %q, want %q", tt.ifs, names, tt.input, name, r.vars[name], tt.want[i])
+			}
+		}
+	}
+
+	// An IFS prefixed to read only applies to it
+	src := `echo x:y | { IFS=: read a b; echo "[$a][$b][$IFS]"; }`
+	if got := runCapture(t, src); got != "[x][y][]\n" {
+		t.Errorf("%q printed %q, want %q", src, got, "[x][y][]\n")
+	}
+}
@@ -0,0 +1,178 @@
+package interp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+)
+
+// runShellProcess runs src in a copy of the test binary acting as the shell,
+// for scripts whose signals would otherwise end the test itself. It returns
+// what the shell printed and the signal that ended it, if any.
+func runShellProcess(t *testing.T, src string) (string, syscall.Signal) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestShellProcess$")
+	cmd.Env = append(os.Environ(), "GSH_TEST_SCRIPT="+src)
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return string(out), ws.Signal()
+		}
+	} else if err != nil {
+		t.Fatalf("running the shell: %v", err)
+	}
+	return string(out), 0
+}
+
+// TestShellProcess is the shell started by runShellProcess.
+func TestShellProcess(t *testing.T) {
+	src, ok := os.LookupEnv("GSH_TEST_SCRIPT")
+	if !ok {
+		t.Skip("only run by runShellProcess")
+	}
+	r := New()
+	r.Stdout = os.Stdout
+	r.RunString(context.Background(), src)
+	os.Exit(r.Exit())
+}
+
+func TestBuiltinTrap_SetAndPrint(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	// Simulate: trap 'echo bye' EXIT INT
+	err := callBuiltin(r, []string{"trap", "echo bye", "EXIT", "INT"}, &out, &errOut, nil)
+	if err != nil {
+		t.Fatalf("trap returned error: %v", err)
+	}
+	defer callBuiltin(r, []string{"trap", "-", "EXIT", "INT"}, &out, &errOut, nil)
+
+	out.Reset()
+	if err := callBuiltin(r, []string{"trap", "-p"}, &out, &errOut, nil); err != nil {
+		t.Fatalf("trap -p returned error: %v", err)
+	}
+	want := "trap -- 'echo bye' EXIT\ntrap -- 'echo bye' SIGINT\n"
+	if out.String() != want {
+		t.Errorf("trap -p output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestBuiltinTrap_SignalNumber(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	// Simulate: trap 'echo term' 15
+	err := callBuiltin(r, []string{"trap", "echo term", "15"}, &out, &errOut, nil)
+	if err != nil {
+		t.Fatalf("trap returned error: %v", err)
+	}
+	if r.traps["TERM"] != "echo term" {
+		t.Errorf("r.traps[TERM] = %q, want %q", r.traps["TERM"], "echo term")
+	}
+
+	// Simulate: trap 15 (a numeric first operand resets)
+	callBuiltin(r, []string{"trap", "15"}, &out, &errOut, nil)
+	if _, ok := r.traps["TERM"]; ok {
+		t.Errorf("trap 15 did not reset the TERM trap")
+	}
+}
+
+func TestBuiltinTrap_IgnoreIsInherited(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found in PATH")
+	}
+	// The child signals itself; it only survives if SIGUSR1 is ignored.
+	child := func() string {
+		got, _ := exec.Command("sh", "-c", "kill -USR1 $$; echo alive").Output()
+		return string(got)
+	}
+
+	callBuiltin(r, []string{"trap", "", "USR1"}, &out, &errOut, nil)
+	if got := child(); got != "alive\n" {
+		t.Errorf("child output with USR1 ignored = %q, want %q", got, "alive\n")
+	}
+
+	callBuiltin(r, []string{"trap", "-", "USR1"}, &out, &errOut, nil)
+	if got := child(); got != "" {
+		t.Errorf("child output with USR1 reset = %q, want no output", got)
+	}
+}
+
+func TestBuiltinTrap_ResetRestoresDefault(t *testing.T) {
+	for _, sig := range []syscall.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP} {
+		if signal.Ignored(sig) {
+			// The shell would be started with it ignored, and couldn't trap it
+			continue
+		}
+		name := signalName(sig)
+
+		src := "trap '' " + name + "; kill -" + name + " $$; echo survived"
+		if got, died := runShellProcess(t, src); got != "survived\n" || died != 0 {
+			t.Errorf("%q printed %q and died of %v, want %q and no signal", src, got, died, "survived\n")
+		}
+
+		src = "trap '' " + name + "; trap - " + name + "; kill -" + name + " $$; echo survived"
+		if got, died := runShellProcess(t, src); got != "" || died != sig {
+			t.Errorf("%q printed %q and died of %v, want no output and %v", src, got, died, sig)
+		}
+	}
+}
+
+func TestBuiltinTrap_RunsAfterEachCommand(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"trap 'echo got' TERM; kill -TERM $$; echo after", "got\nafter\n"},
+		// The loop would end on its own after three seconds
+		{"trap 'echo got; exit' TERM; (sleep 0.2; kill -TERM $$) & i=0; while :; do sleep 0.1; i=$((i+1)); [ $i -lt 30 ] || break; done; echo missed", "got\n"},
+	}
+	for _, tt := range tests {
+		if got, died := runShellProcess(t, tt.src); got != tt.want || died != 0 {
+			t.Errorf("%q printed %q and died of %v, want %q and no signal", tt.src, got, died, tt.want)
+		}
+	}
+}
+
+func TestBuiltinTrap_InvalidSignal(t *testing.T) {
+	r := New()
+	var out bytes.Buffer
+	var errOut bytes.Buffer
+
+	err := callBuiltin(r, []string{"trap", "echo hi", "NOTASIG"}, &out, &errOut, nil)
+	if err == nil {
+		t.Errorf("trap should return error for an invalid signal")
+	}
+	want := "trap: NOTASIG: invalid signal specification\n"
+	if errOut.String() != want {
+		t.Errorf("trap stderr = %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestBuiltinTrap_ExitTrapRuns(t *testing.T) {
+	r := New()
+	got := runIn(t, r, "trap 'echo bye $?' EXIT; (exit 4); exit")
+	if got != "" {
+		t.Errorf("output before Exit = %q, want none", got)
+	}
+	var out bytes.Buffer
+	r.Stdout = &out
+	if status := r.Exit(); status != 4 {
+		t.Errorf("Exit() = %d, want 4", status)
+	}
+	if out.String() != "bye 4\n" {
+		t.Errorf("exit trap output = %q, want %q", out.String(), "bye 4\n")
+	}
+}
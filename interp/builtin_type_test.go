@@ -1,4 +1,4 @@
-package main
+package interp
 
 import (
 	"bytes"
@@ -8,12 +8,13 @@ import (
 )
 
 func TestBuiltinType_Builtin(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
 	// Simulate: type echo (builtin)
 	args := []string{"type", "echo"}
-	err := builtins["type"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err != nil {
 		t.Fatalf("type returned error: %v", err)
 	}
@@ -29,6 +30,7 @@ func TestBuiltinType_Builtin(t *testing.T) {
 }
 
 func TestBuiltinType_External(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
@@ -40,7 +42,7 @@ func TestBuiltinType_External(t *testing.T) {
 	}
 
 	args := []string{"type", cmdName}
-	err := builtins["type"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err != nil {
 		t.Fatalf("type returned error: %v", err)
 	}
@@ -58,12 +60,13 @@ func TestBuiltinType_External(t *testing.T) {
 }
 
 func TestBuiltinType_NotFound(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
 	// Simulate: type notarealcommand
 	args := []string{"type", "notarealcommand"}
-	err := builtins["type"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err != nil {
 		t.Fatalf("type returned error: %v", err)
 	}
@@ -79,12 +82,13 @@ func TestBuiltinType_NotFound(t *testing.T) {
 }
 
 func TestBuiltinType_TooManyArgs(t *testing.T) {
+	r := New()
 	var out bytes.Buffer
 	var errOut bytes.Buffer
 
 	// Simulate: type a b
 	args := []string{"type", "a", "b"}
-	err := builtins["type"](args, &out, &errOut, nil)
+	err := callBuiltin(r, args, &out, &errOut, nil)
 	if err == nil {
 		t.Errorf("type should return error for too many arguments")
 	}
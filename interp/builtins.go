@@ -0,0 +1,252 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+// Builtin is a command implemented by the shell itself.
+type Builtin interface {
+	// Run runs the command and returns its exit status. Errors are reported
+	// on c.Stderr.
+	Run(c *Call) int
+}
+
+// BuiltinFunc adapts an ordinary function to the Builtin interface.
+type BuiltinFunc func(c *Call) int
+
+func (f BuiltinFunc) Run(c *Call) int {
+	return f(c)
+}
+
+// Call describes a single run of a builtin: the shell it runs in, its
+// arguments and its standard streams.
+type Call struct {
+	// Runner is the shell, giving access to its variables, working
+	// directory and the status of the previous command.
+	Runner *Runner
+	// Args holds the command name followed by its arguments.
+	Args   []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Context returns the context of the running commands. A builtin that may
+// block should give up once it is done.
+func (c *Call) Context() context.Context {
+	return c.Runner.ctx
+}
+
+// defaultBuiltins holds the builtins every Runner starts out with. Each
+// feature registers its own in an init function.
+var defaultBuiltins = make(map[string]Builtin)
+
+func init() {
+	defaultBuiltins[":"] = BuiltinFunc(func(c *Call) int { return 0 })
+	defaultBuiltins["exit"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		status := r.lastStatus
+		if len(c.Args) > 1 {
+			n, err := strconv.Atoi(c.Args[1])
+			if err != nil {
+				fmt.Fprintf(c.Stderr, "exit: %s: numeric argument required\n", c.Args[1])
+				n = 2
+			}
+			status = n & 0xff
+		}
+		r.exit(status)
+		return status
+	})
+	defaultBuiltins["pwd"] = BuiltinFunc(func(c *Call) int {
+		if c.Runner.Dir == "" {
+			fmt.Fprintln(c.Stderr, "pwd: cannot determine current directory")
+			return 1
+		}
+		fmt.Fprintln(c.Stdout, c.Runner.Dir)
+		return 0
+	})
+	defaultBuiltins["cd"] = BuiltinFunc(func(c *Call) int {
+		if len(c.Args) != 2 {
+			fmt.Fprintln(c.Stderr, "cd: too many arguments")
+			return 1
+		}
+		arg := c.Args[1]
+		if arg == "~" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				fmt.Fprintln(c.Stderr, "cd: cannot determine home directory")
+				return 1
+			}
+			arg = home
+		}
+		dir := c.Runner.resolvePath(arg)
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			fmt.Fprintf(c.Stderr, "cd: %s: No such file or directory\n", arg)
+			return 1
+		}
+		c.Runner.Dir = dir
+		return 0
+	})
+	defaultBuiltins["echo"] = BuiltinFunc(func(c *Call) int {
+		if _, err := fmt.Fprintln(c.Stdout, strings.Join(c.Args[1:], " ")); err != nil {
+			return 1
+		}
+		return 0
+	})
+	defaultBuiltins["read"] = BuiltinFunc(func(c *Call) int {
+		raw := false
+		names := c.Args[1:]
+		if len(names) > 0 && names[0] == "-r" {
+			raw = true
+			names = names[1:]
+		}
+		if len(names) == 0 {
+			names = []string{"REPLY"}
+		}
+		for _, name := range names {
+			if !syntax.IsName(name) {
+				fmt.Fprintf(c.Stderr, "read: `%s': not a valid identifier\n", name)
+				return 1
+			}
+		}
+		line, err := readLine(c.Stdin, raw)
+		ifs, ok := c.Runner.LookupVar("IFS")
+		if !ok {
+			ifs = " \t\n"
+		}
+		fields := readFields(line, ifs, len(names))
+		for i, name := range names {
+			field := ""
+			if i < len(fields) {
+				field = fields[i]
+			}
+			c.Runner.setVar(name, field)
+		}
+		if err != nil {
+			return 1
+		}
+		return 0
+	})
+	defaultBuiltins["type"] = BuiltinFunc(func(c *Call) int {
+		if len(c.Args) != 2 {
+			fmt.Fprintln(c.Stderr, "type: too many arguments")
+			return 1
+		}
+		r, arg := c.Runner, c.Args[1]
+		if value, ok := r.aliases[arg]; ok {
+			fmt.Fprintf(c.Stdout, "%s is aliased to `%s'\n", arg, value)
+		} else if fn, ok := r.functions[arg]; ok {
+			fmt.Fprintf(c.Stdout, "%s is a function\n%s", arg, functionText(fn))
+		} else if _, ok := r.builtins[arg]; ok {
+			fmt.Fprintf(c.Stdout, "%s is a shell builtin\n", arg)
+		} else {
+			fullPath := r.findExecutable(arg)
+			if fullPath != "" {
+				fmt.Fprintf(c.Stdout, "%s is %s\n", arg, fullPath)
+			} else {
+				fmt.Fprintf(c.Stdout, "%s: not found\n", arg)
+			}
+		}
+		return 0
+	})
+}
+
+// RegisterBuiltin makes name run b, adding a builtin or replacing the one
+// already registered under that name.
+func (r *Runner) RegisterBuiltin(name string, b Builtin) {
+	r.builtins[name] = b
+}
+
+// DisableBuiltin removes the builtin registered under name, so that the name
+// runs a function or external command of that name instead.
+func (r *Runner) DisableBuiltin(name string) {
+	delete(r.builtins, name)
+}
+
+// Builtin returns the builtin registered under name, for instance to wrap it
+// in a replacement.
+func (r *Runner) Builtin(name string) (Builtin, bool) {
+	b, ok := r.builtins[name]
+	return b, ok
+}
+
+// Builtins returns the names of the registered builtins, sorted.
+func (r *Runner) Builtins() []string {
+	names := make([]string, 0, len(r.builtins))
+	for name := range r.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readFields splits line into at most n fields at the characters of ifs, the
+// last of which takes the rest of the line. IFS whitespace (spaces, tabs and
+// newlines) around the fields is dropped, and a run of it delimits a field
+// by itself. An empty ifs leaves line whole.
+func readFields(line, ifs string, n int) []string {
+	var space string
+	for _, c := range " \t\n" {
+		if strings.ContainsRune(ifs, c) {
+			space += string(c)
+		}
+	}
+	line = strings.TrimLeft(line, space)
+	var fields []string
+	for len(fields) < n-1 {
+		end := strings.IndexAny(line, ifs)
+		if end < 0 {
+			break
+		}
+		fields = append(fields, line[:end])
+		// Skip the whitespace and at most one other IFS character
+		line = strings.TrimLeft(line[end:], space)
+		if line != "" && strings.IndexByte(ifs, line[0]) >= 0 && strings.IndexByte(space, line[0]) < 0 {
+			line = strings.TrimLeft(line[1:], space)
+		}
+	}
+	return append(fields, strings.TrimRight(line, space))
+}
+
+// readLine reads a single line from in one byte at a time, so that input after
+// the line is left for the next command. Unless raw is set, a backslash
+// escapes the next character and joins lines when it ends one.
+func readLine(in io.Reader, raw bool) (string, error) {
+	if in == nil {
+		return "", io.EOF
+	}
+	var line strings.Builder
+	var b [1]byte
+	escaped := false
+	for {
+		n, err := in.Read(b[:])
+		if n == 0 {
+			if err == nil {
+				continue
+			}
+			return line.String(), err
+		}
+		switch {
+		case escaped:
+			escaped = false
+			if b[0] != '\n' {
+				line.WriteByte(b[0])
+			}
+		case b[0] == '\\' && !raw:
+			escaped = true
+		case b[0] == '\n':
+			return line.String(), nil
+		default:
+			line.WriteByte(b[0])
+		}
+	}
+}
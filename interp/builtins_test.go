@@ -0,0 +1,76 @@
+package interp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// callBuiltin runs the builtin named by args[0] in r, returning its status as
+// an error like a command's.
+func callBuiltin(r *Runner, args []string, stdout, stderr io.Writer, stdin io.Reader) error {
+	b, ok := r.Builtin(args[0])
+	if !ok {
+		panic("no builtin " + args[0])
+	}
+	return statusErr(b.Run(&Call{Runner: r, Args: args, Stdin: stdin, Stdout: stdout, Stderr: stderr}))
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	r := New()
+	r.RegisterBuiltin("greet", BuiltinFunc(func(c *Call) int {
+		name, _ := c.Runner.LookupVar("NAME")
+		fmt.Fprintf(c.Stdout, "hello %s %s\n", name, strings.Join(c.Args[1:], " "))
+		return 3
+	}))
+	got := runIn(t, r, "NAME=world; greet a b; echo $?; greet | tr a-z A-Z; type greet")
+	want := "hello world a b\n3\nHELLO WORLD \ngreet is a shell builtin\n"
+	if got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if !slices.Contains(r.Builtins(), "greet") {
+		t.Errorf("Builtins() = %v, missing greet", r.Builtins())
+	}
+	if slices.Contains(New().Builtins(), "greet") {
+		t.Errorf("builtin registered with one Runner leaked into a new one")
+	}
+}
+
+func TestRegisterBuiltin_Override(t *testing.T) {
+	r := New()
+	echo, _ := r.Builtin("echo")
+	r.RegisterBuiltin("echo", BuiltinFunc(func(c *Call) int {
+		io.WriteString(c.Stdout, "> ")
+		return echo.Run(c)
+	}))
+	if got, want := runIn(t, r, "echo hi"), "> hi\n"; got != want {
+		t.Errorf("overridden echo printed %q, want %q", got, want)
+	}
+}
+
+func TestDisableBuiltin(t *testing.T) {
+	r := New()
+	r.DisableBuiltin("pwd")
+	if _, ok := r.Builtin("pwd"); ok {
+		t.Fatalf("pwd still registered after DisableBuiltin")
+	}
+	var errOut bytes.Buffer
+	r.Stderr = &errOut
+	// With the builtin gone, pwd runs the external command
+	if got := runIn(t, r, "pwd"); got != r.Dir+"\n" {
+		t.Errorf("pwd printed %q, want %q (stderr %q)", got, r.Dir+"\n", errOut.String())
+	}
+	if got := runIn(t, r, "type pwd"); strings.Contains(got, "builtin") {
+		t.Errorf("type pwd = %q after disabling the builtin", got)
+	}
+}
+
+func TestBuiltins_Sorted(t *testing.T) {
+	names := New().Builtins()
+	if !slices.IsSorted(names) || !slices.Contains(names, "cd") || !slices.Contains(names, "timeout") {
+		t.Errorf("Builtins() = %v", names)
+	}
+}
@@ -0,0 +1,22 @@
+package interp
+
+import "testing"
+
+func TestRunCase(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"x=foo.go; case $x in *.c) echo c;; *.go|*.py) echo go;; esac", "go\n"},
+		{`case 'a*' in "a*") echo quoted;; esac`, "quoted\n"},
+		{`case ab in "a*") echo literal;; a\*) echo escaped;; a?) echo any;; esac`, "any\n"},
+		{"case a in a) echo 1;& b) echo 2;& c) echo 3;; d) echo 4;; esac", "1\n2\n3\n"},
+		{"case ab in a*) echo 1;;& b*) echo 2;;& *b) echo 3;; *) echo 4;; esac", "1\n3\n"},
+		{"case z in a) echo a;; esac; echo $?", "0\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// findExecutable searches for an executable in the PATH and returns its full path if found, or an empty string if not found.
+func (r *Runner) findExecutable(cmd string) string {
+	// Names containing a slash are paths and aren't searched for
+	if strings.ContainsRune(cmd, os.PathSeparator) {
+		if info, err := os.Stat(r.resolvePath(cmd)); err == nil && !info.IsDir() {
+			return cmd
+		}
+		return ""
+	}
+	pathEnv, _ := r.lookupParam("PATH")
+	paths := strings.Split(pathEnv, string(os.PathListSeparator))
+	for _, dir := range paths {
+		fullPath := dir + string(os.PathSeparator) + cmd
+		if _, err := os.Stat(fullPath); err == nil {
+			return fullPath
+		}
+	}
+	return ""
+}
+
+// ShellCmd is a command ready to be started: a builtin or function, run in a
+// goroutine, or an external program.
+type ShellCmd struct {
+	builtinFn func() error // For builtins
+	execCmd   *exec.Cmd    // For externals
+	cleanup   func()       // Closes files opened for redirections, if any
+	group     *procGroup   // The process group of the pipeline or job, if any
+	// tty is the terminal handed to the command's process group while it
+	// runs, if it has a group of its own
+	tty    *os.File
+	done   chan error
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// ctx kills an external command once it is done, giving it killTimeout
+	// to exit after SIGTERM. killTimer sends the SIGKILL.
+	ctx         context.Context
+	killTimeout time.Duration
+	killTimer   *time.Timer
+}
+
+func (c *ShellCmd) Start() error {
+	err := c.start()
+	if err != nil && c.cleanup != nil {
+		c.cleanup()
+	}
+	return err
+}
+
+func (c *ShellCmd) start() error {
+	if c.builtinFn != nil {
+		c.done = make(chan error, 1)
+		go func() {
+			c.done <- c.builtinFn()
+		}()
+		return nil
+	}
+	if c.execCmd != nil {
+		if c.group == nil {
+			if err := c.startExec(); err != nil {
+				return err
+			}
+			if c.tty != nil {
+				giveTerminal(c.tty, c.execCmd.Process.Pid)
+			}
+			return nil
+		}
+		// The processes of a pipeline or job share a process group, led by
+		// the first
+		c.group.mu.Lock()
+		defer c.group.mu.Unlock()
+		c.execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pgid: c.group.pgid}
+		err := c.startExec()
+		if errors.Is(err, syscall.EPERM) && c.group.pgid != 0 {
+			// The group went away with the last of its processes
+			c.execCmd = c.respawn(c.execCmd.Path, c.execCmd.Args)
+			c.execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+			err = c.startExec()
+		}
+		if err != nil {
+			return err
+		}
+		if c.execCmd.SysProcAttr.Pgid == 0 {
+			c.group.pgid = c.execCmd.Process.Pid
+			if c.group.tty != nil {
+				giveTerminal(c.group.tty, c.group.pgid)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("no command to start")
+}
+
+// giveTerminal makes pgid the foreground process group of tty. The group's
+// first process may already have tried to read the terminal, and been
+// stopped for it, so the group is continued.
+func giveTerminal(tty *os.File, pgid int) {
+	if setForeground(tty, pgid) == nil {
+		syscall.Kill(-pgid, syscall.SIGCONT)
+	}
+}
+
+// startExec starts the external command.
+func (c *ShellCmd) startExec() error {
+	c.execCmd.Stdin = c.Stdin
+	c.execCmd.Stdout = c.Stdout
+	c.execCmd.Stderr = c.Stderr
+	err := c.execCmd.Start()
+	if errors.Is(err, syscall.ENOEXEC) {
+		// A file the kernel can't execute (such as a script without a
+		// shebang line) is run as a script by the shell itself.
+		self, selfErr := os.Executable()
+		if selfErr != nil {
+			return err
+		}
+		c.execCmd = c.respawn(self, append([]string{self, c.execCmd.Path}, c.execCmd.Args[1:]...))
+		err = c.execCmd.Start()
+	}
+	return err
+}
+
+// respawn returns a copy of the external command, which failed to start,
+// running path with args (including the name it is run as).
+func (c *ShellCmd) respawn(path string, args []string) *exec.Cmd {
+	cmd := c.command(path, args[1:]...)
+	cmd.Args[0] = args[0]
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = c.Stdin, c.Stdout, c.Stderr
+	cmd.Dir, cmd.Env, cmd.SysProcAttr = c.execCmd.Dir, c.execCmd.Env, c.execCmd.SysProcAttr
+	return cmd
+}
+
+func (c *ShellCmd) Wait() error {
+	if c.cleanup != nil {
+		defer c.cleanup()
+	}
+	if c.builtinFn != nil {
+		return <-c.done
+	}
+	if c.execCmd != nil {
+		err := c.execCmd.Wait()
+		if c.tty != nil {
+			setForeground(c.tty, syscall.Getpgrp())
+		}
+		// A process group may outlive its leader, so a kill that has been
+		// started isn't called off once the command has exited
+		return err
+	}
+	return fmt.Errorf("no command to wait on")
+}
+
+// command returns an exec.Cmd running name that is interrupted when c.ctx is
+// done.
+func (c *ShellCmd) command(name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(c.ctx, name, args...)
+	cmd.Cancel = func() error { return c.interrupt(cmd) }
+	return cmd
+}
+
+// interrupt sends SIGTERM to the process group of cmd, the started external
+// command, and SIGKILL once killTimeout has passed. It may be called as soon
+// as cmd has started, while start is still setting up its group.
+func (c *ShellCmd) interrupt(cmd *exec.Cmd) error {
+	pid := -cmd.Process.Pid
+	if c.group != nil {
+		// start holds the lock until the group's leader is known
+		c.group.mu.Lock()
+		pid = -c.group.pgid
+		c.group.mu.Unlock()
+	}
+	sig := syscall.SIGTERM
+	if c.killTimeout <= 0 {
+		sig = syscall.SIGKILL
+	}
+	if err := syscall.Kill(pid, sig); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	if sig != syscall.SIGKILL {
+		c.killTimer = time.AfterFunc(c.killTimeout, func() {
+			syscall.Kill(pid, syscall.SIGKILL)
+		})
+	}
+	return nil
+}
+
+func (r *Runner) newShellCmd(tokens []string) *ShellCmd {
+	if fn, ok := r.functions[tokens[0]]; ok {
+		cmd := &ShellCmd{}
+		cmd.Stdin = r.Stdin
+		cmd.Stdout = r.Stdout
+		cmd.Stderr = r.Stderr
+		cmd.builtinFn = func() error {
+			return statusErr(r.callFunction(fn, tokens[1:], stdio{cmd.Stdin, cmd.Stdout, cmd.Stderr}))
+		}
+		return cmd
+	}
+	if b, ok := r.builtins[tokens[0]]; ok {
+		cmd := &ShellCmd{}
+		cmd.Stdin = r.Stdin
+		cmd.Stdout = r.Stdout
+		cmd.Stderr = r.Stderr
+		cmd.builtinFn = func() error {
+			return statusErr(b.Run(&Call{Runner: r, Args: tokens, Stdin: cmd.Stdin, Stdout: cmd.Stdout, Stderr: cmd.Stderr}))
+		}
+		return cmd
+	}
+	exe := r.findExecutable(tokens[0])
+	if exe == "" {
+		return nil
+	}
+	c := &ShellCmd{
+		Stdin:       r.Stdin,
+		Stdout:      r.Stdout,
+		Stderr:      r.Stderr,
+		group:       r.group,
+		ctx:         r.ctx,
+		killTimeout: r.KillTimeout,
+	}
+	c.execCmd = c.command(r.resolvePath(exe), tokens[1:]...)
+	c.execCmd.Args[0] = tokens[0]
+	c.execCmd.Dir = r.Dir
+	// Each command gets a process group of its own, so that it can be killed
+	// along with any processes it started. Run from a terminal, the group is
+	// made the terminal's foreground group, which it sends ^C to, while the
+	// command runs.
+	c.execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if r.group == nil {
+		c.tty = foregroundTerminal(r.Stdin)
+	}
+	return c
+}
+
+// exitStatus converts the error returned by a command into a shell exit status.
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var se ExitStatus
+	if errors.As(err, &se) {
+		return int(se)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	return 1
+}
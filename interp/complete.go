@@ -0,0 +1,483 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// compSpec says how to complete the arguments of a command, as set with
+// complete.
+type compSpec struct {
+	actions  []string // kinds of names to offer, such as file or variable
+	wordList string   // -W: words to offer, expanded when completing
+	function string   // -F: function that sets COMPREPLY
+	filter   string   // -X: pattern of words to leave out, or keep with !
+	prefix   string   // -P and -S: added to each completion
+	suffix   string
+	options  []string // -o: how the completions are used, such as nospace
+}
+
+// compActions maps the action flags of complete and compgen to the names
+// -A takes.
+var compActions = map[byte]string{
+	'a': "alias",
+	'b': "builtin",
+	'c': "command",
+	'd': "directory",
+	'e': "export",
+	'f': "file",
+	'k': "keyword",
+	'v': "variable",
+}
+
+// compOptions lists the options -o takes.
+var compOptions = []string{"bashdefault", "default", "dirnames", "filenames", "noquote", "nospace", "plusdirs"}
+
+// shellKeywords lists the reserved words, which compgen -k offers.
+var shellKeywords = []string{
+	"!", "case", "do", "done", "elif", "else", "esac", "fi", "for", "function",
+	"if", "in", "then", "until", "while", "{", "}",
+}
+
+func init() {
+	defaultBuiltins["complete"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		spec, print, remove, names, status := parseCompSpec(c.Args, c.Stderr)
+		if status != 0 {
+			return status
+		}
+		switch {
+		case remove && len(names) == 0:
+			clear(r.compSpecs)
+		case remove:
+			for _, name := range names {
+				delete(r.compSpecs, name)
+			}
+		case print || len(names) == 0:
+			if len(names) == 0 {
+				names = slices.Sorted(maps.Keys(r.compSpecs))
+			}
+			for _, name := range names {
+				s, ok := r.compSpecs[name]
+				if !ok {
+					fmt.Fprintf(c.Stderr, "complete: %s: no completion specification\n", name)
+					status = 1
+					continue
+				}
+				fmt.Fprintf(c.Stdout, "complete %s%s\n", s, quoteWord(name))
+			}
+		default:
+			for _, name := range names {
+				r.compSpecs[name] = spec
+			}
+		}
+		return status
+	})
+
+	defaultBuiltins["compgen"] = BuiltinFunc(func(c *Call) int {
+		spec, _, _, args, status := parseCompSpec(c.Args, c.Stderr)
+		if status != 0 {
+			return status
+		}
+		word := ""
+		if len(args) > 0 {
+			word = args[0]
+		}
+		words := c.Runner.generateCompletions(spec, word, []string{"", word, ""})
+		for _, w := range words {
+			fmt.Fprintln(c.Stdout, w)
+		}
+		if len(words) == 0 {
+			return 1
+		}
+		return 0
+	})
+}
+
+// parseCompSpec parses the options of complete or compgen, returning the
+// spec they describe, whether -p or -r was given, and the operands.
+func parseCompSpec(args []string, stderr io.Writer) (spec *compSpec, print, remove bool, operands []string, status int) {
+	name := args[0]
+	spec = &compSpec{}
+	args = args[1:]
+	usage := func(format string, a ...any) int {
+		fmt.Fprintf(stderr, name+": "+format+"\n", a...)
+		fmt.Fprintf(stderr, "%s: usage: %s [-abcdefkv] [-pr] [-o option] [-A action] [-F function] [-W wordlist] [-X filterpat] [-P prefix] [-S suffix] [name ...]\n", name, name)
+		return 2
+	}
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		opt := args[0]
+		args = args[1:]
+		if opt == "--" {
+			break
+		}
+		for i := 1; i < len(opt); i++ {
+			flag := opt[i]
+			if action, ok := compActions[flag]; ok {
+				spec.actions = append(spec.actions, action)
+				continue
+			}
+			switch flag {
+			case 'p':
+				print = true
+				continue
+			case 'r':
+				remove = true
+				continue
+			case 'o', 'A', 'F', 'W', 'X', 'P', 'S':
+			default:
+				return nil, false, false, nil, usage("-%c: invalid option", flag)
+			}
+			// The flag takes a value: the rest of this argument, or the
+			// next one
+			value := opt[i+1:]
+			if value == "" {
+				if len(args) == 0 {
+					return nil, false, false, nil, usage("-%c: option requires an argument", flag)
+				}
+				value, args = args[0], args[1:]
+			}
+			switch flag {
+			case 'o':
+				if !slices.Contains(compOptions, value) {
+					return nil, false, false, nil, usage("%s: invalid option name", value)
+				}
+				spec.options = append(spec.options, value)
+			case 'A':
+				if !slices.Contains(slices.Collect(maps.Values(compActions)), value) && value != "function" {
+					return nil, false, false, nil, usage("%s: invalid action name", value)
+				}
+				spec.actions = append(spec.actions, value)
+			case 'F':
+				spec.function = value
+			case 'W':
+				spec.wordList = value
+			case 'X':
+				spec.filter = value
+			case 'P':
+				spec.prefix = value
+			case 'S':
+				spec.suffix = value
+			}
+			break
+		}
+	}
+	return spec, print, remove, args, 0
+}
+
+// String formats the spec as the options of the complete command that
+// recreates it, each followed by a space.
+func (s *compSpec) String() string {
+	var b strings.Builder
+	for _, o := range s.options {
+		fmt.Fprintf(&b, "-o %s ", o)
+	}
+	for _, a := range s.actions {
+		fmt.Fprintf(&b, "-A %s ", a)
+	}
+	for _, f := range []struct {
+		flag  string
+		value string
+	}{{"-W", s.wordList}, {"-X", s.filter}, {"-P", s.prefix}, {"-S", s.suffix}, {"-F", s.function}} {
+		if f.value != "" {
+			fmt.Fprintf(&b, "%s %s ", f.flag, quoteWord(f.value))
+		}
+	}
+	return b.String()
+}
+
+// quoteWord quotes s for the shell if it isn't a plain word.
+func quoteWord(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-.,:/+@%") == "" {
+		return s
+	}
+	return shellQuote(s)
+}
+
+// Completion is the result of programmable completion for a word: the words
+// that may replace it, and the -o options of the completion spec that
+// produced them.
+type Completion struct {
+	Words []string
+	// Descriptions, if not nil, has a description to list with each word,
+	// which a completion function gives by following the word with a tab
+	// and the description in COMPREPLY.
+	Descriptions []string
+	// Filenames says the words are file names, to be quoted and shown
+	// with a / after directories.
+	Filenames bool
+	// NoSpace says no space goes after a completed word.
+	NoSpace bool
+	// Default says the shell's usual completion is used if there are no
+	// words, and DirNames that directory names are.
+	Default  bool
+	DirNames bool
+}
+
+// Complete runs the programmable completion set with complete for the word
+// at byte offset point in line, a command line being edited. It reports
+// false if the word isn't an argument of a command with a completion spec.
+// A completion function sees the words of the command in COMP_WORDS, the
+// index of the word in COMP_CWORD, and line and point in COMP_LINE and
+// COMP_POINT; it is called with the command name, the word up to point and
+// the word before it as arguments, and leaves the completions in COMPREPLY.
+func (r *Runner) Complete(ctx context.Context, line string, point int) (*Completion, bool) {
+	words, cword, cur := compWords(line, point)
+	if cword == 0 {
+		return nil, false
+	}
+	spec, ok := r.compSpecs[words[0]]
+	if !ok {
+		if spec, ok = r.compSpecs[path.Base(words[0])]; !ok {
+			return nil, false
+		}
+	}
+
+	saved := r.ctx
+	r.ctx = ctx
+	defer func() { r.ctx = saved }()
+	r.setArray("COMP_WORDS", words)
+	r.setVar("COMP_CWORD", strconv.Itoa(cword))
+	r.setVar("COMP_LINE", line)
+	r.setVar("COMP_POINT", strconv.Itoa(point))
+	defer func() {
+		for _, name := range []string{"COMP_WORDS", "COMP_CWORD", "COMP_LINE", "COMP_POINT", "COMPREPLY"} {
+			r.unsetVar(name)
+		}
+	}()
+
+	prev := words[cword-1]
+	c := &Completion{Words: r.generateCompletions(spec, unquoteWord(cur), []string{words[0], cur, prev})}
+	for i, w := range c.Words {
+		if word, desc, ok := strings.Cut(w, "\t"); ok {
+			if c.Descriptions == nil {
+				c.Descriptions = make([]string, len(c.Words))
+			}
+			c.Words[i], c.Descriptions[i] = word, desc
+		}
+	}
+	for _, o := range spec.options {
+		switch o {
+		case "filenames":
+			c.Filenames = true
+		case "nospace":
+			c.NoSpace = true
+		case "default", "bashdefault":
+			c.Default = true
+		case "dirnames":
+			c.DirNames = true
+		}
+	}
+	return c, true
+}
+
+// compWords splits the command of line that point is in into words, as
+// typed, and returns them with the index of the word point is in and that
+// word's text up to point. A point after a blank starts a new, empty word.
+func compWords(line string, point int) (words []string, cword int, cur string) {
+	point = min(point, len(line))
+	cword = -1
+	var quote byte
+	start := -1 // the start of the current word, or -1 between words
+	end := func(i int) {
+		if start >= 0 {
+			words = append(words, line[start:i])
+			if start <= point && point <= i {
+				cword, cur = len(words)-1, line[start:point]
+			}
+			start = -1
+		}
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if i == point && cword < 0 && start < 0 && strings.IndexByte(" \t\n;&|()", c) >= 0 {
+			// The cursor is between words
+			words = append(words, "")
+			cword = len(words) - 1
+		}
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+			continue
+		case c == '\\':
+			if start < 0 {
+				start = i
+			}
+			i++
+			continue
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ' ' || c == '\t' || c == '\n':
+			end(i)
+			continue
+		case strings.IndexByte(";&|()", c) >= 0:
+			end(i)
+			if i < point {
+				// A new command starts
+				words, cword, cur = nil, -1, ""
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	end(len(line))
+	if cword < 0 {
+		words = append(words, "")
+		cword = len(words) - 1
+	}
+	return words, cword, cur
+}
+
+// generateCompletions returns the completions spec gives for the word cur.
+// A completion function is called with args: the command name, the word as
+// typed and the word before it.
+func (r *Runner) generateCompletions(spec *compSpec, cur string, args []string) []string {
+	var out []string
+	add := func(candidates ...string) {
+		for _, c := range candidates {
+			if strings.HasPrefix(c, cur) {
+				out = append(out, c)
+			}
+		}
+	}
+	for _, action := range spec.actions {
+		switch action {
+		case "alias":
+			add(r.Aliases()...)
+		case "builtin":
+			add(r.Builtins()...)
+		case "function":
+			add(slices.Sorted(maps.Keys(r.functions))...)
+		case "keyword":
+			add(shellKeywords...)
+		case "variable":
+			names := slices.Collect(maps.Keys(r.vars))
+			names = slices.AppendSeq(names, maps.Keys(r.env))
+			names = slices.AppendSeq(names, maps.Keys(r.arrays))
+			slices.Sort(names)
+			add(slices.Compact(names)...)
+		case "export":
+			add(slices.Sorted(maps.Keys(r.env))...)
+		case "command":
+			names := slices.Concat(r.Aliases(), r.Builtins(), slices.Collect(maps.Keys(r.functions)), shellKeywords, r.pathCommands(cur))
+			slices.Sort(names)
+			add(slices.Compact(names)...)
+		case "file", "directory":
+			out = append(out, r.completeFileNames(cur, action == "directory")...)
+		}
+	}
+	if spec.wordList != "" {
+		list, _ := r.expandWord(spec.wordList, true)
+		add(list...)
+	}
+	if spec.function != "" {
+		if fn, ok := r.functions[spec.function]; ok {
+			r.unsetVar("COMPREPLY")
+			// As in bash, the function can't read the terminal or write
+			// over the line being edited: only COMPREPLY is used
+			r.callFunction(fn, args, stdio{nil, io.Discard, r.Stderr})
+			reply, _ := r.arrayWords("COMPREPLY[@]")
+			out = append(out, reply...)
+		} else {
+			fmt.Fprintf(r.Stderr, "%s: %s: function not found\n", r.Name, spec.function)
+		}
+	}
+
+	if spec.filter != "" {
+		pattern, keep := strings.CutPrefix(spec.filter, "!")
+		// & stands for the word being completed
+		pattern = strings.ReplaceAll(pattern, "&", cur)
+		out = slices.DeleteFunc(out, func(w string) bool { return matchPattern(pattern, w) != keep })
+	}
+	for i := range out {
+		out[i] = spec.prefix + out[i] + spec.suffix
+	}
+	return out
+}
+
+// pathCommands returns the names of the executable files in PATH starting
+// with prefix.
+func (r *Runner) pathCommands(prefix string) []string {
+	var names []string
+	pathEnv, _ := r.lookupParam("PATH")
+	for _, dir := range filepath.SplitList(pathEnv) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Name(), prefix) || e.IsDir() {
+				continue
+			}
+			if info, err := e.Info(); err == nil && info.Mode()&0o111 != 0 {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	return names
+}
+
+// completeFileNames returns the files, or only the directories, whose paths
+// start with prefix, relative to the working directory. Names starting with
+// a dot are only included if the prefix of their name does too.
+func (r *Runner) completeFileNames(prefix string, dirsOnly bool) []string {
+	dir, base := path.Split(prefix)
+	entries, err := os.ReadDir(r.resolvePath(dir))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) || strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if dirsOnly {
+			// Following symbolic links
+			info, err := os.Stat(filepath.Join(r.resolvePath(dir), name))
+			if err != nil || !info.IsDir() {
+				continue
+			}
+		}
+		names = append(names, dir+name)
+	}
+	return names
+}
+
+// unquoteWord removes the quotes and backslashes from a word being typed,
+// which may have an unclosed quote, without expanding it.
+func unquoteWord(s string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '\'' && c == '\'', quote == '"' && c == '"':
+			quote = 0
+		case quote == '\'':
+			b.WriteByte(c)
+		case c == '\\' && i+1 < len(s) && (quote == 0 || strings.IndexByte("$`\"\\", s[i+1]) >= 0):
+			i++
+			b.WriteByte(s[i])
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package interp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompgen(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"f1", "f2", "d1/x"} {
+		name = filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(name), 0755)
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`compgen -W "start stop status" -- st`, "start\nstop\nstatus\n"},
+		{`compgen -W "start stop status" sta`, "start\nstatus\n"},
+		{`compgen -f f`, "f1\nf2\n"},
+		{`compgen -d`, "d1\n"},
+		{`compgen -f d1/`, "d1/x\n"},
+		{`xa=1 xb=2; compgen -v x`, "xa\nxb\n"},
+		{`compgen -W "a.go b.txt c.go" -X "*.go"`, "b.txt\n"},
+		{`compgen -W "a.go b.txt c.go" -X "!*.go"`, "a.go\nc.go\n"},
+		{`compgen -W "a b" -P "<" -S ">"`, "<a>\n<b>\n"},
+		{`compgen -W "a b" z; echo $?`, "1\n"},
+		{`f() { COMPREPLY=(one two); }; compgen -F f`, "one\ntwo\n"},
+		{`complete -W "a b" -o nospace svc; complete -p svc`, "complete -o nospace -W 'a b' svc\n"},
+		{`complete -F _f x y; complete -r x; complete -p`, "complete -F _f y\n"},
+		{`complete -p nothing 2>/dev/null; echo $?`, "1\n"},
+	}
+	for _, tt := range tests {
+		r := New()
+		r.Dir = dir
+		if got := runIn(t, r, tt.src); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRunnerComplete(t *testing.T) {
+	r := New()
+	runIn(t, r, `
+_tool() {
+	local cur prev
+	cur=${COMP_WORDS[COMP_CWORD]}
+	prev=${COMP_WORDS[COMP_CWORD-1]}
+	case $prev in
+	--env) COMPREPLY=( $(compgen -W "dev prod" -- "$cur") ) ;;
+	*) COMPREPLY=( $(compgen -W "build deploy --env" -- "$cur") ) ;;
+	esac
+	seen="$1|$2|$3|$COMP_CWORD|$COMP_LINE|$COMP_POINT"
+}
+complete -F _tool tool
+complete -W "x y" -o filenames other
+`)
+
+	tests := []struct {
+		line string
+		want []string
+		seen string
+	}{
+		{"tool ", []string{"build", "deploy", "--env"}, "tool||tool|1|tool |5"},
+		{"tool d", []string{"deploy"}, "tool|d|tool|1|tool d|6"},
+		{"tool build --env ", []string{"dev", "prod"}, "tool||--env|3|tool build --env |17"},
+		{"tool build --env p", []string{"prod"}, "tool|p|--env|3|tool build --env p|18"},
+		{"x=1 tool | tool b", []string{"build"}, "tool|b|tool|1|x=1 tool | tool b|17"},
+		{"/usr/bin/other ", []string{"x", "y"}, ""},
+	}
+	for _, tt := range tests {
+		runIn(t, r, "seen=")
+		c, ok := r.Complete(context.Background(), tt.line, len(tt.line))
+		if !ok {
+			t.Errorf("Complete(%q) found no spec", tt.line)
+			continue
+		}
+		if !slices.Equal(c.Words, tt.want) {
+			t.Errorf("Complete(%q) = %q, want %q", tt.line, c.Words, tt.want)
+		}
+		if seen, _ := r.LookupVar("seen"); seen != tt.seen {
+			t.Errorf("Complete(%q) called the function with %q, want %q", tt.line, seen, tt.seen)
+		}
+	}
+	if c, _ := r.Complete(context.Background(), "other ", 6); !c.Filenames {
+		t.Errorf("-o filenames not reported")
+	}
+	if _, ok := r.Complete(context.Background(), "tool", 4); ok {
+		t.Errorf("completed a command name with a spec")
+	}
+	if _, ok := r.Complete(context.Background(), "cat ", 4); ok {
+		t.Errorf("completed a command without a spec")
+	}
+	for _, name := range []string{"COMP_WORDS", "COMP_CWORD", "COMP_LINE", "COMPREPLY"} {
+		if _, ok := r.LookupVar(name); ok {
+			t.Errorf("%s is still set after completion", name)
+		}
+	}
+}
+
+func TestRunnerComplete_Stdio(t *testing.T) {
+	r := New()
+	runIn(t, r, `
+_noisy() {
+	if read line; then COMPREPLY=(read); else COMPREPLY=(eof); fi
+	echo noise
+	cat
+}
+complete -F _noisy noisy
+`)
+	var out strings.Builder
+	r.Stdin = strings.NewReader("typed ahead\n")
+	r.Stdout = &out
+	c, _ := r.Complete(context.Background(), "noisy ", 6)
+	if want := []string{"eof"}; !slices.Equal(c.Words, want) {
+		t.Errorf("completion function read stdin: got %q, want %q", c.Words, want)
+	}
+	if out.Len() != 0 {
+		t.Errorf("completion function wrote %q to stdout", out.String())
+	}
+}
+
+func TestCompWords(t *testing.T) {
+	tests := []struct {
+		line  string
+		point int
+		words []string
+		cword int
+		cur   string
+	}{
+		{"git ", 4, []string{"git", ""}, 1, ""},
+		{"git co", 6, []string{"git", "co"}, 1, "co"},
+		{"git commit -m 'a b' --am", 24, []string{"git", "commit", "-m", "'a b'", "--am"}, 4, "--am"},
+		{"ls; git s", 9, []string{"git", "s"}, 1, "s"},
+		{"git a b", 5, []string{"git", "a", "b"}, 1, "a"},
+		{"git  b", 4, []string{"git", "", "b"}, 1, ""},
+	}
+	for _, tt := range tests {
+		words, cword, cur := compWords(tt.line, tt.point)
+		if !slices.Equal(words, tt.words) || cword != tt.cword || cur != tt.cur {
+			t.Errorf("compWords(%q, %d) = %q, %d, %q, want %q, %d, %q",
+				tt.line, tt.point, words, cword, cur, tt.words, tt.cword, tt.cur)
+		}
+	}
+}
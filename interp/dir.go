@@ -0,0 +1,12 @@
+package interp
+
+import "path/filepath"
+
+// resolvePath returns path made absolute relative to the shell's working
+// directory.
+func (r *Runner) resolvePath(path string) string {
+	if filepath.IsAbs(path) || r.Dir == "" {
+		return path
+	}
+	return filepath.Join(r.Dir, path)
+}
@@ -0,0 +1,570 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+// stdio is the set of standard streams a command runs with.
+type stdio struct {
+	in       io.Reader
+	out, err io.Writer
+}
+
+// statusErr converts an exit status into the error returned by a ShellCmd.
+func statusErr(status int) error {
+	if status == 0 {
+		return nil
+	}
+	return ExitStatus(status)
+}
+
+// runList runs each and-or list in l and returns the status of the last one.
+// An empty list leaves the status unchanged.
+func (r *Runner) runList(l *syntax.List, st stdio) int {
+	status := r.lastStatus
+	for _, item := range l.Items {
+		if item.Background {
+			status = r.runBackground(item.AndOr, st)
+		} else {
+			status = r.runAndOr(item.AndOr, st)
+		}
+		r.lastStatus = status
+		if r.interrupted() {
+			break
+		}
+	}
+	return status
+}
+
+func (r *Runner) runAndOr(ao *syntax.AndOr, st stdio) int {
+	status := 0
+	for i, pl := range ao.Pipelines {
+		if r.interrupted() {
+			break
+		}
+		if i > 0 {
+			if op := ao.Ops[i-1]; (op == "&&") != (status == 0) {
+				continue
+			}
+		}
+		last := i == len(ao.Pipelines)-1
+		if !last {
+			r.noErrExit++
+		}
+		status = r.runPipeline(pl, st)
+		if !last {
+			r.noErrExit--
+		}
+		r.lastStatus = status
+		r.runPendingTraps()
+	}
+	return status
+}
+
+// runBackground starts ao as a background job in a subshell and returns
+// immediately.
+func (r *Runner) runBackground(ao *syntax.AndOr, st stdio) int {
+	sub := r.subshell()
+	sub.group = &procGroup{}
+	var cmd *ShellCmd
+	var cancel context.CancelFunc
+	if len(ao.Pipelines) == 1 && len(ao.Pipelines[0].Cmds) == 1 && !ao.Pipelines[0].Negate {
+		if sc, ok := ao.Pipelines[0].Cmds[0].(*syntax.SimpleCommand); ok {
+			var status int
+			cmd, status = sub.prepareSimple(sc, stdio{nil, st.out, st.err})
+			if cmd == nil {
+				return status
+			}
+		}
+	}
+	if cmd == nil {
+		sub.ctx, cancel = context.WithCancel(sub.ctx)
+		cmd = &ShellCmd{group: sub.group, builtinFn: func() error {
+			sub.runAndOr(ao, stdio{nil, st.out, st.err})
+			return statusErr(sub.Exit())
+		}}
+	}
+	j, err := r.startJob(cmd, ao.Text, cancel)
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		fmt.Fprintf(st.err, "%s: %v\n", ao.Text, err)
+		return 126
+	}
+	if r.Interactive {
+		if pgid := j.pgid(); pgid != 0 {
+			fmt.Fprintf(st.err, "[%d] %d\n", j.id, pgid)
+		} else {
+			fmt.Fprintf(st.err, "[%d]\n", j.id)
+		}
+	}
+	return 0
+}
+
+// runPipeline runs the commands of pl concurrently, each reading the output of
+// the one before it, and returns the status of the last command.
+func (r *Runner) runPipeline(pl *syntax.Pipeline, st stdio) int {
+	if pl.Negate {
+		r.noErrExit++
+	}
+	var status int
+	if len(pl.Cmds) == 1 {
+		status = r.runCommand(pl.Cmds[0], st)
+	} else {
+		status = r.runStages(pl.Cmds, st)
+	}
+	if pl.Negate {
+		r.noErrExit--
+		if status == 0 {
+			status = 1
+		} else {
+			status = 0
+		}
+		return status
+	}
+
+	// Compound commands have already checked the commands inside them
+	if _, simple := pl.Cmds[0].(*syntax.SimpleCommand); (simple || len(pl.Cmds) > 1) && status != 0 && r.noErrExit == 0 && !r.returning && !r.exited {
+		r.lastStatus = status
+		r.runTrap("ERR")
+		if r.options["errexit"] && !r.inTrap {
+			r.exit(status)
+		}
+	}
+	return status
+}
+
+// runStages runs each command of a pipeline in its own subshell, so that
+// changes a stage makes to the shell's state don't outlive the pipeline.
+func (r *Runner) runStages(cmds []syntax.Command, st stdio) int {
+	started := make([]*ShellCmd, len(cmds))
+	statuses := make([]int, len(cmds))
+	in := st.in
+	// The stages share a process group, unless the pipeline is part of a
+	// job, which has one already
+	group := r.group
+	if group == nil {
+		group = &procGroup{tty: foregroundTerminal(r.Stdin)}
+	}
+	for i, c := range cmds {
+		// Each stage owns the pipe ends it was given and closes them once
+		// they have been handed to a process or the stage has finished.
+		var owned []*os.File
+		if f, ok := in.(*os.File); ok && i > 0 {
+			owned = append(owned, f)
+		}
+		out := st.out
+		var next *os.File
+		if i < len(cmds)-1 {
+			pr, pw, err := os.Pipe()
+			if err != nil {
+				fmt.Fprintf(st.err, "%s: %v\n", r.Name, err)
+				return 1
+			}
+			out, next = pw, pr
+			owned = append(owned, pw)
+		}
+		closeOwned := func() {
+			for _, f := range owned {
+				f.Close()
+			}
+		}
+		stage := stdio{in, out, st.err}
+		in = next
+
+		sub := r.subshell()
+		sub.group = group
+		var cmd *ShellCmd
+		if sc, ok := c.(*syntax.SimpleCommand); ok {
+			cmd, statuses[i] = sub.prepareSimple(sc, stage)
+		} else {
+			c := c
+			cmd = &ShellCmd{builtinFn: func() error {
+				sub.runCommand(c, stage)
+				return statusErr(sub.Exit())
+			}}
+		}
+		if cmd == nil {
+			closeOwned()
+			continue
+		}
+		if cmd.builtinFn != nil {
+			fn := cmd.builtinFn
+			cmd.builtinFn = func() error {
+				defer closeOwned()
+				return fn()
+			}
+		}
+		if err := cmd.Start(); err != nil {
+			fmt.Fprintf(st.err, "%s: %v\n", r.Name, err)
+			statuses[i] = 126
+			closeOwned()
+			continue
+		}
+		if cmd.execCmd != nil {
+			closeOwned()
+		}
+		started[i] = cmd
+	}
+	for i, cmd := range started {
+		if cmd != nil {
+			statuses[i] = exitStatus(cmd.Wait())
+		}
+	}
+	if group.tty != nil && group.pgid != 0 {
+		setForeground(group.tty, syscall.Getpgrp())
+	}
+	return statuses[len(statuses)-1]
+}
+
+// runCommand runs a single command with its redirections applied.
+func (r *Runner) runCommand(c syntax.Command, st stdio) int {
+	if sc, ok := c.(*syntax.SimpleCommand); ok {
+		return r.runSimple(sc, st)
+	}
+	st, cleanup, err := r.applyRedirects(c.Redirects(), st)
+	if err != nil {
+		fmt.Fprintf(st.err, "%s: %v\n", r.Name, err)
+		return 1
+	}
+	defer cleanup()
+
+	switch c := c.(type) {
+	case *syntax.IfClause:
+		return r.runIf(c, st)
+	case *syntax.WhileClause:
+		return r.runWhile(c, st)
+	case *syntax.ForClause:
+		return r.runFor(c, st)
+	case *syntax.ArithForClause:
+		return r.runArithFor(c, st)
+	case *syntax.CaseClause:
+		return r.runCase(c, st)
+	case *syntax.BraceGroup:
+		return r.runList(c.Body, st)
+	case *syntax.Subshell:
+		return r.runSubshell(c, st)
+	case *syntax.FuncDef:
+		r.functions[c.Name] = c
+	}
+	return 0
+}
+
+func (r *Runner) runIf(c *syntax.IfClause, st stdio) int {
+	r.noErrExit++
+	status := r.runList(c.Cond, st)
+	r.noErrExit--
+	if status == 0 {
+		return r.runList(c.Body, st)
+	}
+	for _, elif := range c.Elifs {
+		r.noErrExit++
+		status := r.runList(elif.Cond, st)
+		r.noErrExit--
+		if status == 0 {
+			return r.runList(elif.Body, st)
+		}
+	}
+	if c.Else != nil {
+		return r.runList(c.Else, st)
+	}
+	return 0
+}
+
+func (r *Runner) runSimple(c *syntax.SimpleCommand, st stdio) int {
+	cmd, status := r.prepareSimple(c, st)
+	if cmd == nil {
+		return status
+	}
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(st.err, "%s: %v\n", c.Words[0], err)
+		return 126
+	}
+	return exitStatus(cmd.Wait())
+}
+
+// runCase runs the body of the first item with a pattern matching the word.
+// Its status is that of the last body run, or 0 if no pattern matched.
+func (r *Runner) runCase(c *syntax.CaseClause, st stdio) int {
+	fields, err := r.expandWord(c.Word, false)
+	if err != nil {
+		return r.expansionFailed(err, st)
+	}
+	word := strings.Join(fields, "")
+
+	status := 0
+	for i := 0; i < len(c.Items); i++ {
+		matched, err := r.caseItemMatches(c.Items[i], word)
+		if err != nil {
+			return r.expansionFailed(err, st)
+		}
+		if !matched {
+			continue
+		}
+		// ;& runs the following bodies without testing their patterns
+		for {
+			status = 0
+			if len(c.Items[i].Body.Items) > 0 {
+				status = r.runList(c.Items[i].Body, st)
+			}
+			if c.Items[i].Term != ";&" || i+1 == len(c.Items) {
+				break
+			}
+			i++
+		}
+		// ;;& goes on testing the patterns of the following items
+		if c.Items[i].Term != ";;&" {
+			break
+		}
+	}
+	return status
+}
+
+func (r *Runner) caseItemMatches(item syntax.CaseItem, word string) (bool, error) {
+	for _, raw := range item.Patterns {
+		pattern, err := r.expandPattern(raw)
+		if err != nil {
+			return false, err
+		}
+		if matchPattern(pattern, word) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// prepareSimple expands c and returns the command ready to be started with
+// its redirections and environment applied. If there is nothing to start, it
+// returns nil and the command's exit status.
+func (r *Runner) prepareSimple(c *syntax.SimpleCommand, st stdio) (*ShellCmd, int) {
+	var assigns [][2]string
+	// arrayAssigns holds the assignments to arrays or elements, and
+	// appending ones, which are expanded as they are performed
+	var arrayAssigns [][2]string
+	for _, a := range c.Assigns {
+		name, raw, _ := strings.Cut(a, "=")
+		if !syntax.IsName(name) || strings.HasPrefix(raw, "(") {
+			arrayAssigns = append(arrayAssigns, [2]string{name, raw})
+			continue
+		}
+		value, err := r.expandWord(raw, false)
+		if err != nil {
+			return nil, r.expansionFailed(err, st)
+		}
+		assigns = append(assigns, [2]string{name, strings.Join(value, "")})
+	}
+	var args []string
+	for _, w := range c.Words {
+		fields, err := r.expandWord(w, true)
+		if err != nil {
+			return nil, r.expansionFailed(err, st)
+		}
+		args = append(args, fields...)
+	}
+
+	st, cleanup, err := r.applyRedirects(c.Redirs, st)
+	if err != nil {
+		fmt.Fprintf(st.err, "%s: %v\n", r.Name, err)
+		return nil, 1
+	}
+	if len(args) == 0 {
+		cleanup()
+		for _, a := range assigns {
+			r.setVar(a[0], a[1])
+		}
+		for _, a := range arrayAssigns {
+			if err := r.assign(a[0], a[1]); err != nil {
+				return nil, r.expansionFailed(err, st)
+			}
+		}
+		return nil, 0
+	}
+
+	r.runTrap("DEBUG")
+	if r.options["xtrace"] {
+		fmt.Fprintln(st.err, traceLine(r.tracePrefix(), append(assignWords(assigns), args...)))
+	}
+	cmd := r.newShellCmd(args)
+	if cmd == nil {
+		cleanup()
+		fmt.Fprintf(st.err, "%s: command not found\n", args[0])
+		return nil, 127
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = st.in, st.out, st.err
+	if cmd.execCmd != nil {
+		cmd.execCmd.Env = r.Environ()
+		for _, a := range assigns {
+			cmd.execCmd.Env = append(cmd.execCmd.Env, a[0]+"="+a[1])
+		}
+	} else if len(assigns) > 0 {
+		run := cmd.builtinFn
+		cmd.builtinFn = func() error {
+			defer r.setTempVars(assigns)()
+			return run()
+		}
+	}
+	cmd.cleanup = cleanup
+	return cmd, 0
+}
+
+// setTempVars exports the assignments prefixed to a function or builtin for
+// as long as it runs. It returns a function that puts back the variables they
+// replaced.
+func (r *Runner) setTempVars(assigns [][2]string) func() {
+	saved := make(map[string]savedVar, len(assigns))
+	for _, a := range assigns {
+		if _, ok := saved[a[0]]; !ok {
+			saved[a[0]] = r.saveVar(a[0])
+		}
+		delete(r.vars, a[0])
+		r.env[a[0]] = a[1]
+	}
+	return func() {
+		for name, v := range saved {
+			r.restoreVar(name, v)
+		}
+	}
+}
+
+func assignWords(assigns [][2]string) []string {
+	var words []string
+	for _, a := range assigns {
+		words = append(words, a[0]+"="+a[1])
+	}
+	return words
+}
+
+// expansionFailed reports an expansion error. A non-interactive shell exits,
+// as POSIX requires.
+func (r *Runner) expansionFailed(err error, st stdio) int {
+	fmt.Fprintf(st.err, "%s: %v\n", r.Name, err)
+	if !r.Interactive {
+		r.exit(1)
+	}
+	return 1
+}
+
+// applyRedirects returns st with redirs applied, and a function that closes
+// the files they opened.
+func (r *Runner) applyRedirects(redirs []syntax.Redirect, st stdio) (stdio, func(), error) {
+	var opened []*os.File
+	cleanup := func() {
+		for _, f := range opened {
+			f.Close()
+		}
+	}
+	for _, rd := range redirs {
+		fields, err := r.expandWord(rd.Target, false)
+		if err != nil {
+			cleanup()
+			return st, nil, err
+		}
+		target := strings.Join(fields, "")
+
+		var f io.ReadWriter
+		switch rd.Op {
+		case "<":
+			file, err := os.Open(r.resolvePath(target))
+			if err != nil {
+				cleanup()
+				return st, nil, fmt.Errorf("%s: %w", target, unwrapPathError(err))
+			}
+			opened = append(opened, file)
+			f = file
+		case ">", ">|", ">>":
+			flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+			if rd.Op == ">>" {
+				flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			}
+			file, err := os.OpenFile(r.resolvePath(target), flags, 0644)
+			if err != nil {
+				cleanup()
+				return st, nil, fmt.Errorf("%s: %w", target, unwrapPathError(err))
+			}
+			opened = append(opened, file)
+			f = file
+		case ">&", "<&":
+			stream, err := dupTarget(target, st)
+			if err != nil {
+				cleanup()
+				return st, nil, err
+			}
+			if err := setStream(&st, rd.Fd, stream); err != nil {
+				cleanup()
+				return st, nil, err
+			}
+			continue
+		}
+		if err := setStream(&st, rd.Fd, f); err != nil {
+			cleanup()
+			return st, nil, err
+		}
+	}
+	return st, cleanup, nil
+}
+
+// dupTarget resolves the target of a >& or <& redirection to one of the
+// current streams.
+func dupTarget(target string, st stdio) (any, error) {
+	switch target {
+	case "0":
+		return st.in, nil
+	case "1":
+		return st.out, nil
+	case "2":
+		return st.err, nil
+	}
+	return nil, fmt.Errorf("%s: bad file descriptor", target)
+}
+
+// setStream replaces the stream for fd in st.
+func setStream(st *stdio, fd int, stream any) error {
+	switch fd {
+	case 0:
+		r, ok := stream.(io.Reader)
+		if !ok {
+			return fmt.Errorf("%d: bad file descriptor", fd)
+		}
+		st.in = r
+	case 1, 2:
+		w, ok := stream.(io.Writer)
+		if !ok {
+			return fmt.Errorf("%d: bad file descriptor", fd)
+		}
+		if fd == 1 {
+			st.out = w
+		} else {
+			st.err = w
+		}
+	default:
+		return fmt.Errorf("%d: bad file descriptor", fd)
+	}
+	return nil
+}
+
+func unwrapPathError(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	return err
+}
+
+// runCommandLine parses and runs src, returning its exit status.
+func (r *Runner) runCommandLine(src string) int {
+	l, err := r.parse(src)
+	if err != nil {
+		fmt.Fprintf(r.Stderr, "%s: %v\n", r.Name, err)
+		return 2
+	}
+	return r.runList(l, r.stdio())
+}
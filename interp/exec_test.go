@@ -0,0 +1,58 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCommandLine_IfRedirectsWholeCommand(t *testing.T) {
+	r := New()
+	out := filepath.Join(t.TempDir(), "out.txt")
+	src := "if false\nthen echo no\nelif true; then echo one; echo two\nfi > " + out
+	if status := r.runCommandLine(src); status != 0 {
+		t.Fatalf("status = %d, want 0", status)
+	}
+	got, _ := os.ReadFile(out)
+	if string(got) != "one\ntwo\n" {
+		t.Errorf("output = %q, want %q", got, "one\ntwo\n")
+	}
+}
+
+func TestRunCommandLine_IfStatus(t *testing.T) {
+	r := New()
+	tests := []struct {
+		src  string
+		want int
+	}{
+		{"if true; then false; fi", 1},
+		{"if false; then true; fi", 0},
+		{"if false; then true; else false; fi", 1},
+		{"false && true || true", 0},
+		{"! true", 1},
+	}
+	for _, tt := range tests {
+		if got := r.runCommandLine(tt.src); got != tt.want {
+			t.Errorf("r.runCommandLine(%q) = %d, want %d", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRunCommandLine_PrefixAssignments(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`f() { echo "[$gsh_test_x]"; }; gsh_test_x=bar f; echo "[$gsh_test_x]"`, "[bar]\n[]\n"},
+		{`f() { sh -c 'echo $gsh_test_x'; }; gsh_test_x=bar f`, "bar\n"},
+		{"f() { gsh_test_x=inner; }; gsh_test_x=outer; gsh_test_x=tmp f; echo $gsh_test_x", "outer\n"},
+		{"gsh_test_x=bar export -p | grep gsh_test_x; echo \"[$gsh_test_x]\"", "export gsh_test_x='bar'\n[]\n"},
+		{"gsh_test_x=old; gsh_test_x=bar compgen -v gsh_test_x; export -p | grep -c gsh_test_x; echo $gsh_test_x", "gsh_test_x\n0\nold\n"},
+		{"gsh_test_x=bar sh -c 'echo $gsh_test_x'; echo \"[$gsh_test_x]\"", "bar\n[]\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// expandWord expands the parameters, quotes and escapes in input, a word as
+// written, and splits the result into fields. Parameters are expanded as they
+// are read; see lookupParam. It reports expanding an unset parameter while
+// the nounset option is on. Without split, unquoted expansions and
+// blanks are kept in a single word, as for assignment values. With split,
+// words containing unquoted pattern characters are replaced by the paths
+// they match, if any.
+func (r *Runner) expandWord(input string, split bool) ([]string, error) {
+	// "$@" with no positional parameters expands to no word at all
+	if input == `"$@"` && split && len(r.Params) == 0 {
+		return nil, nil
+	}
+	e, err := r.expandFields(input, split)
+	if err != nil || !split {
+		return e.fields, err
+	}
+	var args []string
+	for i, field := range e.fields {
+		if e.globs[i] {
+			if matches := r.globPath(e.patterns[i]); len(matches) > 0 {
+				args = append(args, matches...)
+				continue
+			}
+		}
+		args = append(args, field)
+	}
+	return args, nil
+}
+
+// expandPattern expands a case pattern. Pattern characters that were quoted
+// in input are escaped with a backslash so that they match literally.
+func (r *Runner) expandPattern(input string) (string, error) {
+	e, err := r.expandFields(input, false)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(e.patterns, ""), nil
+}
+
+// expander collects the fields a word expands to, along with each field in
+// the form of a pattern in which quoted characters are escaped.
+type expander struct {
+	fields   []string
+	patterns []string
+	// globs records which fields contain unquoted pattern characters
+	globs []bool
+
+	buf, pat strings.Builder
+	glob     bool
+	// quoted is set once the current field contains quotes, so that it is
+	// kept even if it's empty, as in ''
+	quoted bool
+}
+
+func (e *expander) writeByte(ch byte, quoted bool) {
+	e.buf.WriteByte(ch)
+	if strings.IndexByte("*?[\\", ch) >= 0 {
+		if quoted {
+			e.pat.WriteByte('\\')
+		} else if ch != '\\' {
+			e.glob = true
+		}
+	}
+	e.pat.WriteByte(ch)
+}
+
+func (e *expander) writeString(s string, quoted bool) {
+	for i := 0; i < len(s); i++ {
+		e.writeByte(s[i], quoted)
+	}
+}
+
+// flush ends the current field.
+func (e *expander) flush() {
+	if e.buf.Len() > 0 || e.quoted {
+		e.fields = append(e.fields, e.buf.String())
+		e.patterns = append(e.patterns, e.pat.String())
+		e.globs = append(e.globs, e.glob)
+	}
+	e.buf.Reset()
+	e.pat.Reset()
+	e.glob, e.quoted = false, false
+}
+
+// expandFields does the quote removal, parameter expansion and field
+// splitting of expandWord.
+func (r *Runner) expandFields(input string, split bool) (*expander, error) {
+
+	e := &expander{}
+	inSingleQuotes, inDoubleQuotes := false, false
+
+	for i := 0; i < len(input); i++ {
+		ch := input[i]
+		quoted := inSingleQuotes || inDoubleQuotes
+
+		switch ch {
+		case '\'':
+			if !inDoubleQuotes {
+				inSingleQuotes = !inSingleQuotes
+				e.quoted = true
+			} else {
+				e.writeByte(ch, true)
+			}
+		case '"':
+			if !inSingleQuotes {
+				inDoubleQuotes = !inDoubleQuotes
+				e.quoted = true
+			} else {
+				e.writeByte(ch, true)
+			}
+		case '\\':
+			if inDoubleQuotes && i+1 < len(input) {
+				next := input[i+1]
+				// Only escape \, $, " or newline inside double quotes
+				if next == '\\' || next == '$' || next == '"' || next == '\n' {
+					i++
+					e.writeByte(next, true)
+				} else {
+					e.writeByte(ch, true)
+				}
+			} else if !inSingleQuotes && !inDoubleQuotes && i+1 < len(input) {
+				i++
+				e.writeByte(input[i], true)
+			} else {
+				e.writeByte(ch, quoted)
+			}
+		case '$', '`':
+			if inSingleQuotes {
+				e.writeByte(ch, true)
+				break
+			}
+			var value string
+			switch {
+			case ch == '`':
+				end := strings.IndexByte(input[i+1:], '`')
+				if end < 0 {
+					e.writeByte(ch, quoted)
+					continue
+				}
+				value = r.commandOutput(input[i+1 : i+1+end])
+				i += end + 1
+			case strings.HasPrefix(input[i:], "$(("):
+				end := matchingParen(input, i+3, 2)
+				if end < 0 {
+					e.writeByte(ch, quoted)
+					continue
+				}
+				n, err := r.evalArith(input[i+3 : end-1])
+				if err != nil {
+					return e, err
+				}
+				value = strconv.FormatInt(n, 10)
+				i = end
+			case strings.HasPrefix(input[i:], "$("):
+				end := matchingParen(input, i+2, 1)
+				if end < 0 {
+					e.writeByte(ch, quoted)
+					continue
+				}
+				value = r.commandOutput(input[i+2 : end])
+				i = end
+			default:
+				name, n := scanParamName(input[i+1:])
+				if n == 0 {
+					e.writeByte(ch, quoted)
+					continue
+				}
+				i += n
+				if inDoubleQuotes && split && (name == "@" || strings.HasSuffix(name, "[@]")) {
+					// "$@" and "${name[@]}" expand to one word per element
+					words := r.Params
+					if name != "@" {
+						words, _ = r.arrayWords(name)
+					}
+					for j, p := range words {
+						if j > 0 {
+							e.flush()
+							e.quoted = true
+						}
+						e.writeString(p, true)
+					}
+					continue
+				}
+				var set bool
+				value, set = r.lookupParam(name)
+				if !set && r.options["nounset"] {
+					return e, fmt.Errorf("%s: unbound variable", name)
+				}
+			}
+			if inDoubleQuotes || !split {
+				e.writeString(value, inDoubleQuotes)
+				break
+			}
+			// Unquoted expansions are split into fields on whitespace
+			if value != "" && strings.TrimLeft(value, " \t\n") != value && e.buf.Len() > 0 {
+				e.flush()
+			}
+			for j, field := range strings.Fields(value) {
+				if j > 0 {
+					e.flush()
+				}
+				e.writeString(field, false)
+			}
+			if value != "" && strings.TrimRight(value, " \t\n") != value && e.buf.Len() > 0 {
+				e.flush()
+			}
+		case '#':
+			// An unquoted # at the start of a word begins a comment
+			if !quoted && e.buf.Len() == 0 && (i == 0 || strings.IndexByte(" \t\n", input[i-1]) >= 0) {
+				// It runs to the end of the line, within an array's list
+				for i+1 < len(input) && input[i+1] != '\n' {
+					i++
+				}
+			} else {
+				e.writeByte(ch, quoted)
+			}
+		case ' ', '\t', '\n':
+			if quoted || !split {
+				e.writeByte(ch, quoted)
+			} else {
+				e.flush()
+			}
+		default:
+			e.writeByte(ch, quoted)
+		}
+
+	}
+
+	e.flush()
+	return e, nil
+}
+
+// matchingParen returns the index in s of the last of the depth closing
+// parentheses that end the substitution whose text starts at s[start], or -1
+// if it isn't closed. Parentheses in quotes don't count.
+func matchingParen(s string, start, depth int) int {
+	var quote byte
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '\\':
+			i++
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// commandOutput runs src in a subshell and returns what it writes to
+// standard output, without trailing newlines, as for $(src).
+func (r *Runner) commandOutput(src string) string {
+	sub := r.subshell()
+	defer r.restoreSignals(sub)
+	var out strings.Builder
+	sub.Stdout = &out
+	sub.lastStatus = sub.runCommandLine(src)
+	sub.Exit()
+	return strings.TrimRight(out.String(), "\n")
+}
@@ -0,0 +1,231 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+// maxFuncDepth limits how deeply functions may call each other, so runaway
+// recursion fails instead of exhausting memory.
+const maxFuncDepth = 1000
+
+// savedVar is the state of a variable before a local declaration or an
+// assignment prefixed to a command hid it.
+type savedVar struct {
+	value    string
+	set, env bool
+}
+
+func init() {
+	defaultBuiltins["return"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		if r.funcDepth == 0 && r.sourceDepth == 0 {
+			fmt.Fprintln(c.Stderr, "return: can only `return' from a function or sourced script")
+			return 1
+		}
+		status := r.lastStatus
+		if len(c.Args) > 1 {
+			n, err := strconv.Atoi(c.Args[1])
+			if err != nil {
+				fmt.Fprintf(c.Stderr, "return: %s: numeric argument required\n", c.Args[1])
+				n = 2
+			}
+			status = n & 0xff
+		}
+		r.returning, r.returnStatus = true, status
+		return status
+	})
+	defaultBuiltins["local"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		if r.funcDepth == 0 {
+			fmt.Fprintln(c.Stderr, "local: can only be used in a function")
+			return 1
+		}
+		return r.declareVars(c.Args, c.Stderr)
+	})
+	defaultBuiltins["declare"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		mode := ""
+		operands := c.Args[1:]
+		for len(operands) > 0 && strings.HasPrefix(operands[0], "-") {
+			opt := operands[0]
+			operands = operands[1:]
+			if opt == "--" {
+				break
+			}
+			if opt != "-f" && opt != "-F" {
+				fmt.Fprintf(c.Stderr, "declare: %s: invalid option\n", opt)
+				fmt.Fprintln(c.Stderr, "declare: usage: declare [-fF] [name[=value] ...]")
+				return 2
+			}
+			mode = opt
+		}
+		switch {
+		case mode != "":
+			return r.printFunctions(operands, mode == "-F", c.Stdout)
+		case len(operands) == 0:
+			r.printVars(c.Stdout)
+			return r.printFunctions(nil, false, c.Stdout)
+		case r.funcDepth > 0:
+			// Variables declared in a function are local to it
+			return r.declareVars(append([]string{"declare"}, operands...), c.Stderr)
+		}
+		for _, arg := range operands {
+			name, value, hasValue := strings.Cut(arg, "=")
+			if !syntax.IsName(name) {
+				fmt.Fprintf(c.Stderr, "declare: `%s': not a valid identifier\n", arg)
+				return 1
+			}
+			if hasValue {
+				r.setVar(name, value)
+			}
+		}
+		return 0
+	})
+}
+
+// Functions returns the names of the defined functions, sorted.
+func (r *Runner) Functions() []string {
+	names := make([]string, 0, len(r.functions))
+	for name := range r.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// callFunction runs fn with args as its positional parameters and returns its
+// exit status.
+func (r *Runner) callFunction(fn *syntax.FuncDef, args []string, st stdio) int {
+	if r.funcDepth >= maxFuncDepth {
+		fmt.Fprintf(st.err, "%s: %s: maximum function nesting level exceeded (%d)\n", r.Name, fn.Name, maxFuncDepth)
+		return 1
+	}
+	savedParams, savedLoopDepth := r.Params, r.loopDepth
+	r.Params, r.loopDepth = args, 0
+	r.funcDepth++
+	r.localScopes = append(r.localScopes, nil)
+	defer func() {
+		scope := r.localScopes[len(r.localScopes)-1]
+		r.localScopes = r.localScopes[:len(r.localScopes)-1]
+		for name, v := range scope {
+			r.restoreVar(name, v)
+		}
+		r.funcDepth--
+		r.Params, r.loopDepth = savedParams, savedLoopDepth
+	}()
+
+	status := r.runCommand(fn.Body, st)
+	if r.returning {
+		r.returning = false
+		status = r.returnStatus
+	}
+	r.lastStatus = status
+	r.runTrap("RETURN")
+	return status
+}
+
+// declareVars implements local, and declare within a function: each
+// name[=value] operand hides the variable's current value until the running
+// function returns.
+func (r *Runner) declareVars(args []string, stderr io.Writer) int {
+	status := 0
+	scope := r.localScopes[len(r.localScopes)-1]
+	if scope == nil {
+		scope = make(map[string]savedVar)
+		r.localScopes[len(r.localScopes)-1] = scope
+	}
+	for _, arg := range args[1:] {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !syntax.IsName(name) {
+			fmt.Fprintf(stderr, "%s: `%s': not a valid identifier\n", args[0], arg)
+			status = 1
+			continue
+		}
+		if _, ok := scope[name]; !ok {
+			scope[name] = r.saveVar(name)
+			// Without a value the local variable starts out unset
+			if !hasValue {
+				delete(r.env, name)
+				delete(r.vars, name)
+			}
+		}
+		if hasValue {
+			r.setVar(name, value)
+		}
+	}
+	return status
+}
+
+// saveVar returns the state of a variable, for restoreVar to put back.
+func (r *Runner) saveVar(name string) savedVar {
+	var v savedVar
+	if v.value, v.env = r.env[name]; v.env {
+		v.set = true
+	} else {
+		v.value, v.set = r.vars[name]
+	}
+	return v
+}
+
+// restoreVar puts back a variable hidden by a local declaration or an
+// assignment prefixed to a command.
+func (r *Runner) restoreVar(name string, v savedVar) {
+	delete(r.vars, name)
+	if v.env {
+		r.env[name] = v.value
+		return
+	}
+	delete(r.env, name)
+	if v.set {
+		r.vars[name] = v.value
+	}
+}
+
+// functionText returns the definition of fn as printed by type and
+// declare -f.
+func functionText(fn *syntax.FuncDef) string {
+	return fn.Name + " () \n" + fn.Text + "\n"
+}
+
+// printFunctions prints the definitions of the named functions, or of every
+// function if names is empty. With namesOnly, only their names are printed.
+func (r *Runner) printFunctions(names []string, namesOnly bool, w io.Writer) int {
+	if len(names) == 0 {
+		for name := range r.functions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	status := 0
+	for _, name := range names {
+		fn, ok := r.functions[name]
+		if !ok {
+			status = 1
+			continue
+		}
+		if namesOnly {
+			fmt.Fprintf(w, "declare -f %s\n", name)
+		} else {
+			fmt.Fprint(w, functionText(fn))
+		}
+	}
+	return status
+}
+
+// printVars prints the shell's own variables so they can be read back in.
+func (r *Runner) printVars(w io.Writer) {
+	names := make([]string, 0, len(r.vars))
+	for name := range r.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s=%s\n", name, shellQuote(r.vars[name]))
+	}
+}
@@ -0,0 +1,59 @@
+package interp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunFunctions(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`f() { echo "$# $1 $2"; }; f a 'b c'; echo "$#"`, "2 a b c\n0\n"},
+		{"f() { echo a; return 3; echo b; }; f; echo $?", "a\n3\n"},
+		{"f() { false; return; }; f; echo $?", "1\n"},
+		{"f() { for i in 1 2 3; do [ $i = 2 ] && return 5; echo $i; done; }; f; echo $?", "1\n5\n"},
+		{"gsh_test_x=global; g() { echo $gsh_test_x; }; f() { local gsh_test_x=local; g; }; f; echo $gsh_test_x", "local\nglobal\n"},
+		{"f() { local gsh_test_x; echo \"[$gsh_test_x]\"; }; gsh_test_x=set; f", "[]\n"},
+		{"f() { echo in f; }; f | tr a-z A-Z", "IN F\n"},
+		{"function f { echo kw; }; f", "kw\n"},
+		{"f() { f; }; f 2>/dev/null; echo $?", "1\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestReturnOutsideFunction(t *testing.T) {
+	r := New()
+	var stderr strings.Builder
+	err := callBuiltin(r, []string{"return"}, &strings.Builder{}, &stderr, nil)
+	if err == nil || !strings.Contains(stderr.String(), "can only `return' from a function") {
+		t.Errorf("return outside a function: err = %v, stderr = %q", err, stderr.String())
+	}
+}
+
+func TestPrintFunctions(t *testing.T) {
+	r := New()
+	r.runCommandLine("greet() { echo hi; }")
+	var out strings.Builder
+	callBuiltin(r, []string{"type", "greet"}, &out, &out, nil)
+	if want := "greet is a function\ngreet () \n{ echo hi; }\n"; out.String() != want {
+		t.Errorf("type greet printed %q, want %q", out.String(), want)
+	}
+	out.Reset()
+	callBuiltin(r, []string{"declare", "-f", "greet"}, &out, &out, nil)
+	if want := "greet () \n{ echo hi; }\n"; out.String() != want {
+		t.Errorf("declare -f greet printed %q, want %q", out.String(), want)
+	}
+	out.Reset()
+	if err := callBuiltin(r, []string{"declare", "-F", "greet", "missing"}, &out, &out, nil); err == nil {
+		t.Errorf("declare -F of a missing function should fail")
+	}
+	if want := "declare -f greet\n"; out.String() != want {
+		t.Errorf("declare -F greet printed %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,222 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// charClasses are the named classes allowed in bracket expressions, as in
+// [[:digit:]].
+var charClasses = map[string]func(rune) bool{
+	"alnum":  func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha":  unicode.IsLetter,
+	"blank":  func(r rune) bool { return r == ' ' || r == '\t' },
+	"cntrl":  unicode.IsControl,
+	"digit":  unicode.IsDigit,
+	"graph":  func(r rune) bool { return unicode.IsGraphic(r) && !unicode.IsSpace(r) },
+	"lower":  unicode.IsLower,
+	"print":  unicode.IsPrint,
+	"punct":  unicode.IsPunct,
+	"space":  unicode.IsSpace,
+	"upper":  unicode.IsUpper,
+	"xdigit": func(r rune) bool { return strings.ContainsRune("0123456789abcdefABCDEF", r) },
+}
+
+// matchPattern reports whether s matches the shell pattern, in which * matches
+// any string, ? any character, [...] a bracket expression and a backslash
+// quotes the next character. It is used for both case patterns and pathname
+// expansion.
+func matchPattern(pattern, s string) bool {
+	// On a mismatch, retry from the most recent * with it matching one more
+	// character of s.
+	starP, starS := -1, -1
+	p, i := 0, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				starP, starS = p, i
+				p++
+				continue
+			case '?':
+				_, size := utf8.DecodeRuneInString(s[i:])
+				p++
+				i += size
+				continue
+			case '[':
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if matched, end, ok := matchBracket(pattern, p, r); ok {
+					if matched {
+						p = end
+						i += size
+						continue
+					}
+				} else if s[i] == '[' {
+					// An unterminated bracket is an ordinary character
+					p++
+					i++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) && pattern[p+1] == s[i] {
+					p += 2
+					i++
+					continue
+				}
+			default:
+				if pattern[p] == s[i] {
+					p++
+					i++
+					continue
+				}
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		_, size := utf8.DecodeRuneInString(s[starS:])
+		starS += size
+		p, i = starP+1, starS
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchBracket matches r against the bracket expression starting at
+// pattern[start]. It returns whether r matched, the offset just past the
+// expression, and false if the expression is unterminated.
+func matchBracket(pattern string, start int, r rune) (matched bool, end int, ok bool) {
+	i := start + 1
+	negate := false
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		negate = true
+		i++
+	}
+	first := true
+	for i < len(pattern) {
+		if pattern[i] == ']' && !first {
+			return matched != negate, i + 1, true
+		}
+		first = false
+
+		if strings.HasPrefix(pattern[i:], "[:") {
+			if end := strings.Index(pattern[i+2:], ":]"); end >= 0 {
+				if class, ok := charClasses[pattern[i+2:i+2+end]]; ok {
+					if class(r) {
+						matched = true
+					}
+					i += end + 4
+					continue
+				}
+			}
+		}
+
+		lo, size := bracketChar(pattern, i)
+		i += size
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi, size = bracketChar(pattern, i+1)
+			i += size + 1
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+	return false, 0, false
+}
+
+// bracketChar decodes the possibly backslash-escaped character at pattern[i].
+func bracketChar(pattern string, i int) (rune, int) {
+	if pattern[i] == '\\' && i+1 < len(pattern) {
+		r, size := utf8.DecodeRuneInString(pattern[i+1:])
+		return r, size + 1
+	}
+	return utf8.DecodeRuneInString(pattern[i:])
+}
+
+// hasPatternChars reports whether pattern contains unescaped *, ? or [.
+func hasPatternChars(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// unescapePattern removes the backslashes quoting characters in pattern.
+func unescapePattern(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
+
+// globPath returns the sorted paths matching pattern, one path component at
+// a time. Names starting with a dot only match a pattern component that
+// starts with a dot too. Relative patterns are matched in the shell's
+// working directory.
+func (r *Runner) globPath(pattern string) []string {
+	dirs := []string{""}
+	if strings.HasPrefix(pattern, "/") {
+		dirs = []string{"/"}
+	}
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		last := i == len(parts)-1
+		var next []string
+		for _, dir := range dirs {
+			if !hasPatternChars(part) {
+				path := dir + unescapePattern(part)
+				if _, err := os.Lstat(r.resolvePath(path)); err == nil {
+					next = append(next, path)
+				}
+				continue
+			}
+			entries, err := os.ReadDir(r.resolvePath(filepath.Clean(dir + ".")))
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				name := entry.Name()
+				if strings.HasPrefix(name, ".") && !strings.HasPrefix(part, ".") {
+					continue
+				}
+				if matchPattern(part, name) {
+					next = append(next, dir+name)
+				}
+			}
+		}
+		dirs = dirs[:0]
+		for _, path := range next {
+			if last {
+				dirs = append(dirs, path)
+			} else if info, err := os.Stat(r.resolvePath(path)); err == nil && info.IsDir() {
+				dirs = append(dirs, path+"/")
+			}
+		}
+	}
+	if strings.HasSuffix(pattern, "/") {
+		for i := range dirs {
+			dirs[i] = strings.TrimSuffix(dirs[i], "/") + "/"
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
@@ -0,0 +1,68 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"a*c", "abbc", true},
+		{"a*c", "abcd", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"[a-c]x", "bx", true},
+		{"[!a-c]x", "bx", false},
+		{"[^a-c]x", "dx", true},
+		{"[]]", "]", true},
+		{"[[:digit:]]*", "7up", true},
+		{"[[:upper:]]", "a", false},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+		{"[ab", "[ab", true},
+		{"*.go", "main.go", true},
+	}
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestExpandWord_Pathnames(t *testing.T) {
+	r := New()
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt", ".hidden.txt", "sub/c.txt"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{dir + "/*.txt", []string{dir + "/a.txt", dir + "/b.txt"}},
+		{dir + "/.*.txt", []string{dir + "/.hidden.txt"}},
+		{dir + "/*/c.txt", []string{dir + "/sub/c.txt"}},
+		{dir + "/*.md", []string{dir + "/*.md"}},
+		{"'" + dir + "/*.txt'", []string{dir + "/*.txt"}},
+	}
+	for _, tt := range tests {
+		got, err := r.expandWord(tt.word, true)
+		if err != nil {
+			t.Fatalf("r.expandWord(%q) returned error: %v", tt.word, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("r.expandWord(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExpandHistory performs csh-style history expansion on a line typed at the
+// prompt, before it is parsed and added to the history:
+//
+//	!!         the previous command      !n, !-n    command n, or n back
+//	!string    the last command starting with string
+//	!?string?  the last command containing string
+//	^old^new   the previous command with old replaced by new
+//
+// An event may be followed by a word designator (:n, :x-y, ^, $, *) and by
+// modifiers (:h, :t, :r, :e, :s/old/new/, :gs/old/new/). A ! is left alone
+// inside single quotes, after a backslash or $, or before a blank, = or (. The
+// returned line is unchanged if it has no history references.
+func (r *Runner) ExpandHistory(line string) (string, error) {
+	if rest, ok := strings.CutPrefix(line, "^"); ok {
+		// ^old^new^ is short for !!:s^old^new^
+		text, err := r.historyEvent("!!", r.lastHistory)
+		if err != nil {
+			return "", err
+		}
+		text, n, err := r.substituteHistory(text, rest, '^', false)
+		if err != nil {
+			return "", err
+		}
+		return text + rest[n:], nil
+	}
+
+	var out strings.Builder
+	inSingle, inDouble := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && !inSingle && i+1 < len(line):
+			out.WriteString(line[i : i+2])
+			i++
+			continue
+		case c == '\'' && !inDouble:
+			inSingle = !inSingle
+		case c == '"' && !inSingle:
+			inDouble = !inDouble
+		case c == '!' && !inSingle && i+1 < len(line) && !strings.ContainsRune(" \t\n=(", rune(line[i+1])) &&
+			!(inDouble && line[i+1] == '"') && !strings.HasSuffix(line[:i], "$") && !strings.HasSuffix(line[:i], "${"):
+			text, n, err := r.expandHistoryRef(line[i+1:])
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(text)
+			i += n
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), nil
+}
+
+// histWordEnd lists the characters that end the string of a !string event.
+const histWordEnd = " \t\n:;&|<>()'\"`"
+
+// expandHistoryRef expands the history reference s follows a ! in, and
+// returns its text and the number of bytes of s it took up.
+func (r *Runner) expandHistoryRef(s string) (string, int, error) {
+	var text string
+	var err error
+	j := 0
+	switch {
+	case s[0] == '!':
+		j = 1
+		text, err = r.historyEvent("!!", r.lastHistory)
+	case strings.ContainsRune(":^$*", rune(s[0])):
+		// A word designator alone refers to the previous command
+		text, err = r.historyEvent("!!", r.lastHistory)
+	case s[0] == '-' || isDigit(s[0]):
+		j = 1
+		for j < len(s) && isDigit(s[j]) {
+			j++
+		}
+		n, convErr := strconv.Atoi(s[:j])
+		if convErr != nil {
+			return "", 0, fmt.Errorf("!%s: event not found", s[:j])
+		}
+		if n < 0 {
+			n += len(r.history) + 1
+		}
+		text, err = r.historyEvent("!"+s[:j], func() (string, bool) {
+			if n < 1 || n > len(r.history) {
+				return "", false
+			}
+			return r.history[n-1].line, true
+		})
+	case s[0] == '?':
+		end := strings.IndexAny(s[1:], "?\n")
+		if end < 0 {
+			end = len(s) - 1
+		}
+		str := s[1 : end+1]
+		j = min(end+2, len(s))
+		if end+1 < len(s) && s[end+1] == '\n' {
+			j = end + 1
+		}
+		text, err = r.historyEvent("!?"+str, func() (string, bool) {
+			return r.searchHistory(func(line string) bool { return strings.Contains(line, str) })
+		})
+	default:
+		j = strings.IndexAny(s, histWordEnd)
+		if j < 0 {
+			j = len(s)
+		}
+		str := s[:j]
+		text, err = r.historyEvent("!"+str, func() (string, bool) {
+			return r.searchHistory(func(line string) bool { return strings.HasPrefix(line, str) })
+		})
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Word designator
+	if j < len(s) && (strings.ContainsRune("^$*", rune(s[j])) ||
+		s[j] == ':' && j+1 < len(s) && strings.ContainsRune("0123456789^$*-", rune(s[j+1]))) {
+		if s[j] == ':' {
+			j++
+		}
+		words := historyWords(text)
+		n := 0
+		if text, n, err = selectHistoryWords(words, s[j:]); err != nil {
+			return "", 0, err
+		}
+		j += n
+	}
+
+	// Modifiers
+	for j+1 < len(s) && s[j] == ':' {
+		m := s[j+1]
+		j += 2
+		switch m {
+		case 'h':
+			if k := strings.LastIndexByte(text, '/'); k > 0 {
+				text = text[:k]
+			} else if k == 0 {
+				text = "/"
+			}
+		case 't':
+			text = text[strings.LastIndexByte(text, '/')+1:]
+		case 'r':
+			if k := strings.LastIndexByte(text, '.'); k > strings.LastIndexByte(text, '/') {
+				text = text[:k]
+			}
+		case 'e':
+			if k := strings.LastIndexByte(text, '.'); k > strings.LastIndexByte(text, '/') {
+				text = text[k:]
+			} else {
+				text = ""
+			}
+		case 's', 'g':
+			global := m == 'g'
+			if global {
+				if j >= len(s) || s[j] != 's' {
+					return "", 0, fmt.Errorf(":g: unrecognized history modifier")
+				}
+				j++
+			}
+			if j >= len(s) {
+				return "", 0, fmt.Errorf("no previous substitution")
+			}
+			n := 0
+			if text, n, err = r.substituteHistory(text, s[j+1:], s[j], global); err != nil {
+				return "", 0, err
+			}
+			j += 1 + n
+		default:
+			return "", 0, fmt.Errorf(":%c: unrecognized history modifier", m)
+		}
+	}
+	return text, j, nil
+}
+
+// historyEvent returns the history entry find finds, or an event not found
+// error naming ref.
+func (r *Runner) historyEvent(ref string, find func() (string, bool)) (string, error) {
+	line, ok := find()
+	if !ok {
+		return "", fmt.Errorf("%s: event not found", ref)
+	}
+	return line, nil
+}
+
+// lastHistory returns the most recent history entry.
+func (r *Runner) lastHistory() (string, bool) {
+	if len(r.history) == 0 {
+		return "", false
+	}
+	return r.history[len(r.history)-1].line, true
+}
+
+// searchHistory returns the most recent history entry that match accepts.
+func (r *Runner) searchHistory(match func(line string) bool) (string, bool) {
+	for i := len(r.history) - 1; i >= 0; i-- {
+		if match(r.history[i].line) {
+			return r.history[i].line, true
+		}
+	}
+	return "", false
+}
+
+// substituteHistory implements the s modifier: s holds old and new separated
+// and ended by delim, and the last delimiter may be left out at the end of
+// the line. An empty old reuses the previous substitution's, and & in new
+// stands for old. It returns text with old replaced by new, once or
+// everywhere if global, and the number of bytes of s it took up.
+func (r *Runner) substituteHistory(text, s string, delim byte, global bool) (string, int, error) {
+	var parts [2]strings.Builder
+	part, i := 0, 0
+	for ; i < len(s) && part < 2 && s[i] != '\n'; i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == delim || s[i+1] == '&'):
+			if part == 1 && s[i+1] == '&' {
+				parts[part].WriteByte('\\')
+			}
+			parts[part].WriteByte(s[i+1])
+			i++
+		case s[i] == delim:
+			part++
+		default:
+			parts[part].WriteByte(s[i])
+		}
+	}
+	old, repl := parts[0].String(), parts[1].String()
+	if old == "" {
+		old = r.histSubst
+	}
+	if old == "" {
+		return "", 0, fmt.Errorf("no previous substitution")
+	}
+	r.histSubst = old
+	// & stands for old, \& for a literal &
+	repl = strings.ReplaceAll(repl, "&", "\x00")
+	repl = strings.ReplaceAll(repl, "\\\x00", "&")
+	repl = strings.ReplaceAll(repl, "\x00", old)
+	if !strings.Contains(text, old) {
+		return "", 0, fmt.Errorf("%s: substitution failed", old)
+	}
+	n := 1
+	if global {
+		n = -1
+	}
+	return strings.Replace(text, old, repl, n), i, nil
+}
+
+// historyWords splits a history entry into the words a word designator
+// counts: blank-separated words, keeping quoted text together, with each run
+// of the operator characters ;&|<>() as a word of its own.
+func historyWords(line string) []string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			flush()
+		case strings.IndexByte(";&|<>()", c) >= 0:
+			flush()
+			j := i
+			for j < len(line) && strings.IndexByte(";&|<>()", line[j]) >= 0 {
+				j++
+			}
+			words = append(words, line[i:j])
+			i = j - 1
+		case c == '\'' || c == '"':
+			j := len(line)
+			if end := strings.IndexByte(line[i+1:], c); end >= 0 {
+				j = i + end + 2
+			}
+			word.WriteString(line[i:j])
+			i = j - 1
+		case c == '\\' && i+1 < len(line):
+			word.WriteString(line[i : i+2])
+			i++
+		default:
+			word.WriteByte(c)
+		}
+	}
+	flush()
+	return words
+}
+
+// selectHistoryWords parses the word designator at the start of s and
+// returns the words it selects joined by spaces, and its length.
+func selectHistoryWords(words []string, s string) (string, int, error) {
+	last := len(words) - 1
+	first, end, n := 0, 0, 0
+	// number parses a word number or $ at s[n:], or returns def
+	number := func(def int) int {
+		if n < len(s) && s[n] == '$' {
+			n++
+			return last
+		}
+		start := n
+		for n < len(s) && isDigit(s[n]) {
+			n++
+		}
+		if n == start {
+			return def
+		}
+		v, _ := strconv.Atoi(s[start:n])
+		return v
+	}
+	switch s[0] {
+	case '^':
+		first, end, n = 1, 1, 1
+	case '*':
+		// The arguments, which may be none
+		if last < 1 {
+			return "", 1, nil
+		}
+		first, end, n = 1, last, 1
+	case '-':
+		n = 1
+		first, end = 0, number(last)
+	default:
+		first = number(0)
+		end = first
+		switch {
+		case n < len(s) && s[n] == '*':
+			n++
+			end = last
+		case n < len(s) && s[n] == '-':
+			n++
+			end = number(last - 1)
+		}
+	}
+	if first < 0 || first > last || end > last || end < first {
+		return "", 0, fmt.Errorf("%s: bad word specifier", s[:n])
+	}
+	return strings.Join(words[first:end+1], " "), n, nil
+}
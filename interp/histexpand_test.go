@@ -0,0 +1,82 @@
+package interp
+
+import "testing"
+
+func TestExpandHistory(t *testing.T) {
+	r := New()
+	for _, line := range []string{
+		"cd /usr/local/lib",
+		"tar xzf archive.tar.gz -C /tmp",
+		"echo one two 'three four' | wc -w",
+		"ls -l /etc/hosts",
+	} {
+		r.AddHistory(line)
+	}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"echo plain", "echo plain"},
+		{"!!", "ls -l /etc/hosts"},
+		{"sudo !!", "sudo ls -l /etc/hosts"},
+		{"!1", "cd /usr/local/lib"},
+		{"!-2 && true", "echo one two 'three four' | wc -w && true"},
+		{"!cd", "cd /usr/local/lib"},
+		{"!?archive?", "tar xzf archive.tar.gz -C /tmp"},
+		{"!?xzf", "tar xzf archive.tar.gz -C /tmp"},
+		{"cat !$", "cat /etc/hosts"},
+		{"echo !^", "echo -l"},
+		{"echo !*", "echo -l /etc/hosts"},
+		{"echo !!:0", "echo ls"},
+		{"echo !echo:3", "echo 'three four'"},
+		{"echo !echo:1-2", "echo one two"},
+		{"echo !echo:4*", "echo | wc -w"},
+		{"echo !echo:2-", "echo two 'three four' | wc"},
+		{"echo !echo:-1", "echo echo one"},
+		{"echo !tar:2:r:r", "echo archive"},
+		{"echo !tar:2:e", "echo .gz"},
+		{"echo !cd:1:h !cd:1:t", "echo /usr/local lib"},
+		{"!!:s/hosts/passwd/", "ls -l /etc/passwd"},
+		{"!tar:gs/a/A/", "tAr xzf Archive.tAr.gz -C /tmp"},
+		{"!!:s/-l/& -a/", "ls -l -a /etc/hosts"},
+		{"^hosts^group", "ls -l /etc/group"},
+		{"^hosts^group^ /etc/motd", "ls -l /etc/group /etc/motd"},
+		{"echo '!!' \\!! \"!!\"", "echo '!!' \\!! \"ls -l /etc/hosts\""},
+		{"echo $! ${!x} a! b!= !(x)", "echo $! ${!x} a! b!= !(x)"},
+	}
+	for _, tt := range tests {
+		got, err := r.ExpandHistory(tt.line)
+		if err != nil {
+			t.Errorf("ExpandHistory(%q) returned error: %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ExpandHistory(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestExpandHistory_Errors(t *testing.T) {
+	r := New()
+	if _, err := r.ExpandHistory("!!"); err == nil || err.Error() != "!!: event not found" {
+		t.Errorf("!! with no history: err = %v", err)
+	}
+	r.AddHistory("echo a b")
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"!nope", "!nope: event not found"},
+		{"!9", "!9: event not found"},
+		{"!?zzz?", "!?zzz: event not found"},
+		{"!!:5", "5: bad word specifier"},
+		{"!!:x", ":x: unrecognized history modifier"},
+		{"!!:s/zzz/y/", "zzz: substitution failed"},
+		{"^zzz^y", "zzz: substitution failed"},
+	}
+	for _, tt := range tests {
+		if _, err := r.ExpandHistory(tt.line); err == nil || err.Error() != tt.want {
+			t.Errorf("ExpandHistory(%q) error = %v, want %q", tt.line, err, tt.want)
+		}
+	}
+}
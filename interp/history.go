@@ -0,0 +1,312 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// histEntry is a command line in the history and the time it was entered.
+type histEntry struct {
+	line string
+	time time.Time
+}
+
+// defaultHistSize is the number of history entries kept when HISTSIZE isn't
+// set to a number.
+const defaultHistSize = 500
+
+func init() {
+	defaultBuiltins["history"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		args := c.Args[1:]
+		if len(args) == 0 || isNumber(args[0]) {
+			n := len(r.history)
+			if len(args) > 0 {
+				n, _ = strconv.Atoi(args[0])
+			}
+			r.printHistory(c.Stdout, n)
+			return 0
+		}
+		opt, args := args[0], args[1:]
+		file := r.histFile()
+		if opt == "-w" || opt == "-r" || opt == "-a" {
+			if len(args) > 0 {
+				file = args[0]
+			}
+			if file == "" {
+				return 0
+			}
+		}
+		var err error
+		switch opt {
+		case "-c":
+			r.history, r.histAppended = nil, 0
+		case "-d":
+			if len(args) == 0 {
+				fmt.Fprintln(c.Stderr, "history: -d: option requires an argument")
+				return 2
+			}
+			n, convErr := strconv.Atoi(args[0])
+			if n < 0 {
+				n += len(r.history) + 1
+			}
+			if convErr != nil || n < 1 || n > len(r.history) {
+				fmt.Fprintf(c.Stderr, "history: %s: history position out of range\n", args[0])
+				return 1
+			}
+			r.history = slices.Delete(r.history, n-1, n)
+			if n <= r.histAppended {
+				r.histAppended--
+			}
+		case "-w":
+			err = r.writeHistory(file)
+		case "-r":
+			err = r.readHistory(file)
+		case "-a":
+			err = r.appendHistory(file)
+		default:
+			fmt.Fprintf(c.Stderr, "history: %s: invalid option\n", opt)
+			fmt.Fprintln(c.Stderr, "history: usage: history [-c] [-d offset] [n] or history -awr [filename]")
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "history: %s: %v\n", file, unwrapPathError(err))
+			return 1
+		}
+		return 0
+	})
+}
+
+// History returns the command history, oldest first.
+func (r *Runner) History() []string {
+	lines := make([]string, len(r.history))
+	for i, e := range r.history {
+		lines[i] = e.line
+	}
+	return lines
+}
+
+// AddHistory adds a command line to the history, unless HISTCONTROL says to
+// leave it out: ignorespace skips lines starting with a space, ignoredups
+// lines matching the previous entry, and ignoreboth both; erasedups removes
+// earlier copies of the line. The oldest entries are dropped to keep at most
+// HISTSIZE.
+func (r *Runner) AddHistory(line string) {
+	line = strings.TrimRight(line, "\n")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	control, _ := r.lookupParam("HISTCONTROL")
+	for _, opt := range strings.Split(control, ":") {
+		switch opt {
+		case "ignorespace", "ignoreboth":
+			if line[0] == ' ' || line[0] == '\t' {
+				return
+			}
+		}
+		switch opt {
+		case "ignoredups", "ignoreboth":
+			if len(r.history) > 0 && r.history[len(r.history)-1].line == line {
+				return
+			}
+		case "erasedups":
+			for i := len(r.history) - 1; i >= 0; i-- {
+				if r.history[i].line == line {
+					r.history = slices.Delete(r.history, i, i+1)
+					if i < r.histAppended {
+						r.histAppended--
+					}
+				}
+			}
+		}
+	}
+	r.history = append(r.history, histEntry{line, time.Now()})
+	r.trimHistory()
+}
+
+// LoadHistory reads the history file, $HISTFILE, into the history. A missing
+// file is not an error.
+func (r *Runner) LoadHistory() error {
+	file := r.histFile()
+	if file == "" {
+		return nil
+	}
+	if err := r.readHistory(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SaveHistory appends the entries added since the history was loaded or
+// last saved to the history file, $HISTFILE, then truncates the file to its
+// last HISTFILESIZE entries. The file is locked while it is written, so
+// shells sharing it keep each other's entries.
+func (r *Runner) SaveHistory() error {
+	file := r.histFile()
+	if file == "" {
+		return nil
+	}
+	return r.appendHistory(file)
+}
+
+// histFile returns the history file named by HISTFILE, or "" if there is
+// none.
+func (r *Runner) histFile() string {
+	file, _ := r.lookupParam("HISTFILE")
+	if file == "" {
+		return ""
+	}
+	return r.resolvePath(file)
+}
+
+// histLimit returns the number of entries the variable name allows, or -1
+// for no limit. An unset or non-numeric value gives def.
+func (r *Runner) histLimit(name string, def int) int {
+	value, ok := r.lookupParam(name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	switch {
+	case err != nil:
+		return def
+	case n < 0:
+		return -1
+	}
+	return n
+}
+
+// trimHistory drops the oldest entries beyond HISTSIZE.
+func (r *Runner) trimHistory() {
+	limit := r.histLimit("HISTSIZE", defaultHistSize)
+	if limit < 0 || len(r.history) <= limit {
+		return
+	}
+	drop := len(r.history) - limit
+	r.history = slices.Delete(r.history, 0, drop)
+	r.histAppended = max(r.histAppended-drop, 0)
+}
+
+// printHistory lists the last n history entries with their numbers.
+func (r *Runner) printHistory(w io.Writer, n int) {
+	start := max(len(r.history)-n, 0)
+	for i, e := range r.history[start:] {
+		fmt.Fprintf(w, "%5d  %s\n", start+i+1, e.line)
+	}
+}
+
+// readHistory adds the entries in file to the history, ahead of the ones
+// not yet written to a history file so that those are still appended by
+// history -a.
+func (r *Runner) readHistory(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH); err != nil {
+		return err
+	}
+	entries, err := readHistoryEntries(f)
+	if err != nil {
+		return err
+	}
+	r.history = slices.Insert(r.history, r.histAppended, entries...)
+	r.histAppended += len(entries)
+	r.trimHistory()
+	return nil
+}
+
+// writeHistory replaces the contents of file with the whole history.
+func (r *Runner) writeHistory(file string) error {
+	return r.updateHistoryFile(file, func([]histEntry) []histEntry {
+		return r.history
+	})
+}
+
+// appendHistory appends the entries added since the last read, write or
+// append to file.
+func (r *Runner) appendHistory(file string) error {
+	return r.updateHistoryFile(file, func(entries []histEntry) []histEntry {
+		return append(entries, r.history[r.histAppended:]...)
+	})
+}
+
+// updateHistoryFile rewrites file with the entries update returns, given the
+// ones already in it, keeping the last HISTFILESIZE of them. The file is
+// locked throughout, so that shells updating it at the same time don't lose
+// each other's entries.
+func (r *Runner) updateHistoryFile(file string, update func(entries []histEntry) []histEntry) error {
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	entries, err := readHistoryEntries(f)
+	if err != nil {
+		return err
+	}
+	entries = update(entries)
+	if limit := r.histLimit("HISTFILESIZE", r.histLimit("HISTSIZE", defaultHistSize)); limit >= 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	var buf strings.Builder
+	for _, e := range entries {
+		t := e.time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		fmt.Fprintf(&buf, "#%d\n%s\n", t.Unix(), e.line)
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt([]byte(buf.String()), 0); err != nil {
+		return err
+	}
+	r.histAppended = len(r.history)
+	return nil
+}
+
+// readHistoryEntries reads the entries of a history file. Each entry is
+// preceded by a line holding a # and the time it was entered in seconds since
+// the epoch, as bash writes them, which lets an entry span several lines. A
+// file without these lines has an entry on each line.
+func readHistoryEntries(rd io.Reader) ([]histEntry, error) {
+	var entries []histEntry
+	timed := false
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if sec, ok := strings.CutPrefix(line, "#"); ok && isNumber(sec) {
+			n, _ := strconv.ParseInt(sec, 10, 64)
+			entries = append(entries, histEntry{time: time.Unix(n, 0)})
+			timed = true
+			continue
+		}
+		switch {
+		case timed && len(entries) > 0 && entries[len(entries)-1].line != "":
+			entries[len(entries)-1].line += "\n" + line
+		case timed && len(entries) > 0:
+			entries[len(entries)-1].line = line
+		case strings.TrimSpace(line) != "":
+			entries = append(entries, histEntry{line: line})
+		}
+	}
+	// Drop the timestamps of empty entries
+	entries = slices.DeleteFunc(entries, func(e histEntry) bool {
+		return strings.TrimSpace(e.line) == ""
+	})
+	return entries, sc.Err()
+}
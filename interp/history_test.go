@@ -0,0 +1,149 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAddHistory(t *testing.T) {
+	tests := []struct {
+		control string
+		want    []string
+	}{
+		{"", []string{"a", "b", "b", " c", "a"}},
+		{"ignorespace", []string{"a", "b", "b", "a"}},
+		{"ignoredups", []string{"a", "b", " c", "a"}},
+		{"ignoreboth", []string{"a", "b", "a"}},
+		{"erasedups", []string{"b", " c", "a"}},
+		{"ignorespace:erasedups", []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		r := New()
+		r.SetVar("HISTCONTROL", tt.control)
+		for _, line := range []string{"a\n", "b", "b", " c", "", "a"} {
+			r.AddHistory(line)
+		}
+		if got := r.History(); !slices.Equal(got, tt.want) {
+			t.Errorf("HISTCONTROL=%s: history = %q, want %q", tt.control, got, tt.want)
+		}
+	}
+
+	r := New()
+	r.SetVar("HISTSIZE", "2")
+	for _, line := range []string{"a", "b", "c"} {
+		r.AddHistory(line)
+	}
+	if got, want := r.History(), []string{"b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("HISTSIZE=2: history = %q, want %q", got, want)
+	}
+}
+
+func TestHistoryBuiltin(t *testing.T) {
+	r := New()
+	for _, line := range []string{"one", "two", "three", "four"} {
+		r.AddHistory(line)
+	}
+	if got, want := runIn(t, r, "history"), "    1  one\n    2  two\n    3  three\n    4  four\n"; got != want {
+		t.Errorf("history printed %q, want %q", got, want)
+	}
+	if got, want := runIn(t, r, "history 2"), "    3  three\n    4  four\n"; got != want {
+		t.Errorf("history 2 printed %q, want %q", got, want)
+	}
+	if got, want := runIn(t, r, "history | tail -n 1"), "    4  four\n"; got != want {
+		t.Errorf("history in a pipeline printed %q, want %q", got, want)
+	}
+	runIn(t, r, "history -d 2; history -d -1")
+	if got, want := r.History(), []string{"one", "three"}; !slices.Equal(got, want) {
+		t.Errorf("after history -d: %q, want %q", got, want)
+	}
+	if got := runIn(t, r, "history -d 9 2>&1; echo $?"); got != "history: 9: history position out of range\n1\n" {
+		t.Errorf("history -d out of range printed %q", got)
+	}
+	runIn(t, r, "history -c")
+	if got := r.History(); len(got) != 0 {
+		t.Errorf("after history -c: %q, want no entries", got)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	r := New()
+	r.SetVar("HISTFILE", file)
+	r.AddHistory("echo a")
+	r.AddHistory("for i in 1 2\ndo echo $i\ndone")
+	if err := r.SaveHistory(); err != nil {
+		t.Fatalf("SaveHistory: %v", err)
+	}
+	// Saving again only appends what was added since
+	r.AddHistory("echo b")
+	if err := r.SaveHistory(); err != nil {
+		t.Fatalf("SaveHistory: %v", err)
+	}
+
+	r2 := New()
+	r2.SetVar("HISTFILE", file)
+	r2.AddHistory("typed before loading")
+	if err := r2.LoadHistory(); err != nil {
+		t.Fatalf("LoadHistory: %v", err)
+	}
+	want := []string{"echo a", "for i in 1 2\ndo echo $i\ndone", "echo b", "typed before loading"}
+	if got := r2.History(); !slices.Equal(got, want) {
+		t.Errorf("loaded history = %q, want %q", got, want)
+	}
+
+	// history -w rewrites the file, -a appends and HISTFILESIZE caps it
+	other := filepath.Join(t.TempDir(), "other")
+	runIn(t, r2, "history -w "+other)
+	r3 := New()
+	runIn(t, r3, "history -r "+other)
+	if got := r3.History(); !slices.Equal(got, want) {
+		t.Errorf("history -r of a file written by -w = %q, want %q", got, want)
+	}
+	r3.AddHistory("echo c")
+	runIn(t, r3, "HISTFILESIZE=3; history -a "+other)
+	r4 := New()
+	runIn(t, r4, "history -r "+other)
+	if got, want := r4.History(), []string{"echo b", "typed before loading", "echo c"}; !slices.Equal(got, want) {
+		t.Errorf("history after -a with HISTFILESIZE=3 = %q, want %q", got, want)
+	}
+
+	// A plain file with one command on each line reads too
+	plain := filepath.Join(t.TempDir(), "plain")
+	os.WriteFile(plain, []byte("ls\n\ncd /tmp\n"), 0644)
+	r5 := New()
+	runIn(t, r5, "history -r "+plain)
+	if got, want := r5.History(), []string{"ls", "cd /tmp"}; !slices.Equal(got, want) {
+		t.Errorf("plain history file read as %q, want %q", got, want)
+	}
+}
+
+func TestHistoryFile_ConcurrentShells(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history")
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := New()
+			r.SetVar("HISTFILE", file)
+			for j := range 20 {
+				r.AddHistory(strings.Repeat("x", i+1) + " " + string(rune('a'+j)))
+				if err := r.SaveHistory(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	r := New()
+	r.SetVar("HISTFILE", file)
+	r.SetVar("HISTSIZE", "-1")
+	r.LoadHistory()
+	if got := len(r.History()); got != 160 {
+		t.Errorf("history file holds %d entries, want 160", got)
+	}
+}
@@ -0,0 +1,154 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// job is a command started in the background with a trailing "&".
+type job struct {
+	id      int
+	cmd     *ShellCmd
+	command string
+	group   *procGroup
+	// cancel stops the subshell running a compound job
+	cancel context.CancelFunc
+	done   bool
+	status int
+}
+
+// procGroup is the process group of a pipeline or background job. The first
+// external command started leads the group and the others join it, so that
+// they can be signalled as a whole.
+type procGroup struct {
+	mu   sync.Mutex
+	pgid int // 0 until a process has started
+	// tty is the terminal the group is given once it exists, for a
+	// pipeline run in the foreground
+	tty *os.File
+}
+
+// startJob starts cmd in the background and adds it to the job table. cancel,
+// if not nil, stops what cmd runs in the shell, and is called once it is done.
+func (r *Runner) startJob(cmd *ShellCmd, command string, cancel context.CancelFunc) (*job, error) {
+	if cmd.group == nil {
+		cmd.group = &procGroup{}
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	r.jobsMu.Lock()
+	id := 1
+	if len(r.jobs) > 0 {
+		id = r.jobs[len(r.jobs)-1].id + 1
+	}
+	j := &job{id: id, cmd: cmd, command: command, group: cmd.group, cancel: cancel}
+	r.jobs = append(r.jobs, j)
+	r.jobsMu.Unlock()
+
+	go func() {
+		status := exitStatus(cmd.Wait())
+		if cancel != nil {
+			cancel()
+		}
+		r.jobsMu.Lock()
+		j.done, j.status = true, status
+		r.jobsMu.Unlock()
+	}()
+	return j, nil
+}
+
+// pgid returns the ID of the job's process group, or 0 if it hasn't started
+// a process.
+func (j *job) pgid() int {
+	j.group.mu.Lock()
+	defer j.group.mu.Unlock()
+	return j.group.pgid
+}
+
+// findJob resolves a job spec: %n, %% or %+ (the current job), %- (the
+// previous job), %string (command starts with string) or %?string (command
+// contains string).
+func (r *Runner) findJob(spec string) (*job, error) {
+	r.jobsMu.Lock()
+	defer r.jobsMu.Unlock()
+
+	ref := strings.TrimPrefix(spec, "%")
+	switch {
+	case ref == "" || ref == "%" || ref == "+":
+		if len(r.jobs) > 0 {
+			return r.jobs[len(r.jobs)-1], nil
+		}
+	case ref == "-":
+		if len(r.jobs) > 1 {
+			return r.jobs[len(r.jobs)-2], nil
+		}
+	default:
+		if n, err := strconv.Atoi(ref); err == nil {
+			for _, j := range r.jobs {
+				if j.id == n {
+					return j, nil
+				}
+			}
+			break
+		}
+		var found *job
+		for _, j := range r.jobs {
+			var match bool
+			if sub, ok := strings.CutPrefix(ref, "?"); ok {
+				match = strings.Contains(j.command, sub)
+			} else {
+				match = strings.HasPrefix(j.command, ref)
+			}
+			if match {
+				if found != nil {
+					return nil, fmt.Errorf("%s: ambiguous job spec", spec)
+				}
+				found = j
+			}
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+// reportDoneJobs prints a notice for every background job that has finished
+// since the last call and removes it from the job table.
+func (r *Runner) reportDoneJobs(w io.Writer) {
+	r.jobsMu.Lock()
+	defer r.jobsMu.Unlock()
+
+	var running []*job
+	for i, j := range r.jobs {
+		if !j.done {
+			running = append(running, j)
+			continue
+		}
+		state := "Done"
+		if j.status != 0 {
+			state = fmt.Sprintf("Exit %d", j.status)
+		}
+		fmt.Fprintf(w, "[%d]%c  %-24s%s\n", j.id, jobMarker(i, len(r.jobs)), state, j.command)
+	}
+	r.jobs = running
+}
+
+// jobMarker returns '+' for the current job, '-' for the previous job and a
+// space for any other job.
+func jobMarker(i, n int) rune {
+	switch i {
+	case n - 1:
+		return '+'
+	case n - 2:
+		return '-'
+	}
+	return ' '
+}
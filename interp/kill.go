@@ -0,0 +1,164 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func init() {
+	defaultBuiltins["kill"] = BuiltinFunc(func(c *Call) int {
+		r, args := c.Runner, c.Args
+		args = args[1:]
+		if len(args) == 0 {
+			fmt.Fprintln(c.Stderr, "kill: usage: kill [-s sigspec | -n signum | -sigspec] pid | jobspec ... or kill -l [sigspec]")
+			return 1
+		}
+
+		sig := syscall.SIGTERM
+		switch opt := args[0]; {
+		case opt == "-l" || opt == "-L":
+			return listSignals(args[1:], c.Stdout, c.Stderr)
+		case opt == "-s" || opt == "-n":
+			if len(args) < 2 {
+				fmt.Fprintf(c.Stderr, "kill: %s: option requires an argument\n", opt)
+				return 1
+			}
+			s, ok := parseKillSignal(args[1])
+			if !ok || (opt == "-n" && !isNumber(args[1])) {
+				fmt.Fprintf(c.Stderr, "kill: %s: invalid signal specification\n", args[1])
+				return 1
+			}
+			sig, args = s, args[2:]
+		case opt == "--":
+			args = args[1:]
+		case strings.HasPrefix(opt, "-") && len(opt) > 1:
+			s, ok := parseKillSignal(opt[1:])
+			if !ok {
+				fmt.Fprintf(c.Stderr, "kill: %s: invalid signal specification\n", opt[1:])
+				return 1
+			}
+			sig, args = s, args[1:]
+		}
+
+		status := 0
+		for _, target := range args {
+			if err := r.signalTarget(target, sig); err != nil {
+				fmt.Fprintf(c.Stderr, "kill: %v\n", err)
+				status = 1
+			}
+		}
+		return status
+	})
+}
+
+// signalTarget sends sig to a PID or, for a job spec, to the job's process
+// group. A signal that would end a process also stops a compound job's
+// subshell, so that it starts nothing more.
+func (r *Runner) signalTarget(target string, sig syscall.Signal) error {
+	var pid int
+	if strings.HasPrefix(target, "%") {
+		j, err := r.findJob(target)
+		if err != nil {
+			return err
+		}
+		if j.cancel != nil && terminates(sig) {
+			j.cancel()
+		}
+		pid = -j.pgid()
+		if pid == 0 {
+			if j.cancel == nil {
+				return fmt.Errorf("%s: job has no process to signal", target)
+			}
+			// Only the shell is running the job
+			return nil
+		}
+	} else {
+		n, err := strconv.Atoi(target)
+		if err != nil {
+			return fmt.Errorf("%s: arguments must be process or job IDs", target)
+		}
+		pid = n
+	}
+	if pid == syscall.Getpid() {
+		return r.signalSelf(sig)
+	}
+	if err := syscall.Kill(pid, sig); err != nil {
+		var errno syscall.Errno
+		if errors.As(err, &errno) && errno == syscall.ESRCH {
+			return fmt.Errorf("(%s) - No such process", target)
+		}
+		return fmt.Errorf("(%s) - %v", target, err)
+	}
+	return nil
+}
+
+// signalSelf delivers sig to the shell before kill returns, so that a signal
+// left to its default action ends the shell before it runs another command.
+// The runtime passes trapped signals on to r.signals asynchronously, so
+// those are queued for their trap directly.
+func (r *Runner) signalSelf(sig syscall.Signal) error {
+	if r.traps[signalName(sig)] != "" {
+		select {
+		case r.signals <- sig:
+		default:
+		}
+		return nil
+	}
+	return raise(sig)
+}
+
+// terminates reports whether sig ends a process that doesn't handle it.
+func terminates(sig syscall.Signal) bool {
+	switch sig {
+	case syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGKILL, syscall.SIGTERM,
+		syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGPIPE, syscall.SIGALRM:
+		return true
+	}
+	return false
+}
+
+// listSignals implements kill -l. Without operands it lists every signal;
+// otherwise it translates signal numbers (or exit statuses of signalled
+// commands) to names and names to numbers.
+func listSignals(specs []string, stdout, stderr io.Writer) int {
+	if len(specs) == 0 {
+		printSignalList(stdout)
+		return 0
+	}
+	status := 0
+	for _, spec := range specs {
+		if n, e := strconv.Atoi(spec); e == nil {
+			if n > 128 {
+				n -= 128
+			}
+			if s, ok := lookupSignal(strconv.Itoa(n)); ok {
+				fmt.Fprintln(stdout, signalName(s))
+				continue
+			}
+		} else if s, ok := lookupSignal(spec); ok {
+			fmt.Fprintln(stdout, int(s))
+			continue
+		}
+		fmt.Fprintf(stderr, "kill: %s: invalid signal specification\n", spec)
+		status = 1
+	}
+	return status
+}
+
+// parseKillSignal is lookupSignal extended with signal 0, which only checks
+// that the target exists.
+func parseKillSignal(spec string) (syscall.Signal, bool) {
+	if spec == "0" {
+		return 0, true
+	}
+	return lookupSignal(spec)
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
@@ -0,0 +1,15 @@
+package interp
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// raise sends sig to the calling thread, which handles it before the system
+// call returns. A signal sent to the process may instead be handled by
+// another of its threads once kill has already returned.
+func raise(sig syscall.Signal) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	return syscall.Tgkill(syscall.Getpid(), syscall.Gettid(), sig)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package interp
+
+import "syscall"
+
+// raise sends sig to the shell's own process.
+func raise(sig syscall.Signal) error {
+	return syscall.Kill(syscall.Getpid(), sig)
+}
@@ -0,0 +1,157 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+func init() {
+	defaultBuiltins["break"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		return r.loopControl(c.Args, c.Stderr, &r.breakLevels)
+	})
+	defaultBuiltins["continue"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		return r.loopControl(c.Args, c.Stderr, &r.continueLevels)
+	})
+}
+
+// loopControl implements break [n] and continue [n] by setting levels to the
+// number of loops to unwind.
+func (r *Runner) loopControl(args []string, stderr io.Writer, levels *int) int {
+	n := 1
+	if len(args) > 1 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+			fmt.Fprintf(stderr, "%s: %s: loop count out of range\n", args[0], args[1])
+			return 1
+		}
+	}
+	if r.loopDepth == 0 {
+		fmt.Fprintf(stderr, "%s: only meaningful in a `for', `while', or `until' loop\n", args[0])
+		return 0
+	}
+	if n > r.loopDepth {
+		n = r.loopDepth
+	}
+	*levels = n
+	return 0
+}
+
+// interrupted reports whether a break, continue, return or exit is
+// unwinding, or the context of the running commands is done. Lists stop
+// running further commands while it is.
+func (r *Runner) interrupted() bool {
+	return r.breakLevels > 0 || r.continueLevels > 0 || r.returning || r.exited || r.ctx.Err() != nil
+}
+
+// endIteration is called by a loop after each run of its body and reports
+// whether the loop should stop.
+func (r *Runner) endIteration() bool {
+	if r.returning || r.exited || r.ctx.Err() != nil {
+		return true
+	}
+	if r.breakLevels > 0 {
+		r.breakLevels--
+		return true
+	}
+	if r.continueLevels > 0 {
+		r.continueLevels--
+		// continue n stops this loop if it targets an outer one
+		return r.continueLevels > 0
+	}
+	return false
+}
+
+// runWhile runs a while or until loop. A loop's status is that of the last
+// command its body ran, or 0 if the body never ran.
+func (r *Runner) runWhile(c *syntax.WhileClause, st stdio) int {
+	r.loopDepth++
+	defer func() { r.loopDepth-- }()
+
+	status := 0
+	for {
+		r.noErrExit++
+		condStatus := r.runList(c.Cond, st)
+		r.noErrExit--
+		if r.interrupted() {
+			if r.endIteration() {
+				break
+			}
+			continue
+		}
+		if (condStatus == 0) == c.Until {
+			break
+		}
+		status = r.runList(c.Body, st)
+		if r.endIteration() {
+			break
+		}
+	}
+	return status
+}
+
+// runFor runs a for loop, with the same status as runWhile.
+func (r *Runner) runFor(c *syntax.ForClause, st stdio) int {
+	items := r.Params
+	if c.HasIn {
+		items = nil
+		for _, w := range c.Words {
+			fields, err := r.expandWord(w, true)
+			if err != nil {
+				return r.expansionFailed(err, st)
+			}
+			items = append(items, fields...)
+		}
+	}
+
+	r.loopDepth++
+	defer func() { r.loopDepth-- }()
+
+	status := 0
+	for _, item := range items {
+		r.setVar(c.Name, item)
+		status = r.runList(c.Body, st)
+		if r.endIteration() {
+			break
+		}
+	}
+	return status
+}
+
+// runArithFor runs an arithmetic for loop, with the same status as runWhile.
+func (r *Runner) runArithFor(c *syntax.ArithForClause, st stdio) int {
+	if _, err := r.evalArith(c.Init); err != nil {
+		fmt.Fprintf(st.err, "%s: %v\n", r.Name, err)
+		return 1
+	}
+
+	r.loopDepth++
+	defer func() { r.loopDepth-- }()
+
+	status := 0
+	for {
+		if c.Cond != "" {
+			v, err := r.evalArith(c.Cond)
+			if err != nil {
+				fmt.Fprintf(st.err, "%s: %v\n", r.Name, err)
+				return 1
+			}
+			if v == 0 {
+				break
+			}
+		}
+		status = r.runList(c.Body, st)
+		if r.endIteration() {
+			break
+		}
+		if _, err := r.evalArith(c.Post); err != nil {
+			fmt.Fprintf(st.err, "%s: %v\n", r.Name, err)
+			return 1
+		}
+	}
+	return status
+}
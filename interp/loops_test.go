@@ -0,0 +1,57 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// runCapture runs src in a new Runner and returns what it wrote to stdout.
+func runCapture(t *testing.T, src string) string {
+	t.Helper()
+	return runIn(t, New(), src)
+}
+
+// runIn runs src in r with its stdout pointed at a temporary file and
+// returns what it wrote.
+func runIn(t *testing.T, r *Runner, src string) string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r.Stdout = f
+	r.runCommandLine(src)
+
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	return string(got)
+}
+
+func TestRunLoops(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(in, []byte("a 1\nb 2 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"for x in a 'b c'; do echo $x; done", "a\nb c\n"},
+		{"for ((i=0; i<3; i++)); do echo $i; done", "0\n1\n2\n"},
+		{"while read k v; do echo $v-$k; done < " + in, "1-a\n2 3-b\n"},
+		{"for a in 1 2; do for b in x y; do continue 2; echo no; done; echo no; done; echo $a$b", "2x\n"},
+		{"for a in 1 2; do while true; do break 2; done; echo no; done; echo $a", "1\n"},
+		{"for a in 1 2 3; do echo $a; done | while read n; do echo n$n; done", "n1\nn2\nn3\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%q output = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// optionNames lists the options that can be set with -o name (or their
+// single-letter forms) when the shell is started, or with the set builtin.
+var optionNames = map[string]bool{
+	"errexit":      true, // -e: exit when a command fails
+	"histexpand":   true, // -H: expand ! history references at the prompt
+	"menucomplete": true, // choose among completions from a menu at the prompt
+	"nounset":      true, // -u: treat expanding an unset parameter as an error
+	"xtrace":       true, // -x: print each command to stderr before running it
+}
+
+// optionLetters maps single-letter flags to the option they set.
+var optionLetters = map[byte]string{
+	'e': "errexit",
+	'H': "histexpand",
+	'u': "nounset",
+	'x': "xtrace",
+}
+
+func init() {
+	defaultBuiltins["set"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		args := c.Args[1:]
+		if len(args) == 0 {
+			r.printVars(c.Stdout)
+			return 0
+		}
+		setParams := false
+		for len(args) > 0 {
+			arg := args[0]
+			if arg == "--" {
+				args, setParams = args[1:], true
+				break
+			}
+			if len(arg) < 2 || (arg[0] != '-' && arg[0] != '+') {
+				break
+			}
+			args = args[1:]
+			on := arg[0] == '-'
+			for i := 1; i < len(arg); i++ {
+				if arg[i] != 'o' {
+					name, ok := optionLetters[arg[i]]
+					if !ok {
+						fmt.Fprintf(c.Stderr, "set: %c%c: invalid option\n", arg[0], arg[i])
+						fmt.Fprintln(c.Stderr, "set: usage: set [-euxH] [-o option-name] [--] [arg ...]")
+						return 2
+					}
+					r.options[name] = on
+					continue
+				}
+				// Without a name, -o lists the options and +o prints
+				// the commands that would set them as they are
+				if len(args) == 0 || args[0] == "--" || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[0], "+") {
+					r.printOptions(c.Stdout, !on)
+					continue
+				}
+				if err := r.SetOption(args[0], on); err != nil {
+					fmt.Fprintf(c.Stderr, "set: %v\n", err)
+					return 1
+				}
+				args = args[1:]
+			}
+		}
+		if setParams || len(args) > 0 {
+			r.Params = append([]string(nil), args...)
+		}
+		return 0
+	})
+}
+
+// printOptions lists the options and whether they are on, or with asCommands,
+// the set commands that would restore them.
+func (r *Runner) printOptions(w io.Writer, asCommands bool) {
+	names := make([]string, 0, len(optionNames))
+	for name := range optionNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch {
+		case asCommands && r.options[name]:
+			fmt.Fprintf(w, "set -o %s\n", name)
+		case asCommands:
+			fmt.Fprintf(w, "set +o %s\n", name)
+		case r.options[name]:
+			fmt.Fprintf(w, "%-15s\ton\n", name)
+		default:
+			fmt.Fprintf(w, "%-15s\toff\n", name)
+		}
+	}
+}
+
+// SetOption turns the named option, such as errexit, on or off.
+func (r *Runner) SetOption(name string, on bool) error {
+	if _, ok := optionNames[name]; !ok {
+		return fmt.Errorf("%s: invalid option name", name)
+	}
+	r.options[name] = on
+	return nil
+}
+
+// Option reports whether the named option is on.
+func (r *Runner) Option(name string) bool {
+	return r.options[name]
+}
+
+// OptionName returns the name of the option set by the single-letter flag
+// c, as in -e for errexit.
+func OptionName(c byte) (string, bool) {
+	name, ok := optionLetters[c]
+	return name, ok
+}
+
+// optionFlags returns the value of $-: the letters of the options currently
+// set.
+func (r *Runner) optionFlags() string {
+	var flags []byte
+	for c, name := range optionLetters {
+		if r.options[name] {
+			flags = append(flags, c)
+		}
+	}
+	if r.Interactive {
+		flags = append(flags, 'i')
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i] < flags[j] })
+	return string(flags)
+}
+
+// traceLine formats tokens as the line printed by the xtrace option after
+// prefix, quoting words that wouldn't read back as themselves.
+func traceLine(prefix string, tokens []string) string {
+	quoted := make([]string, len(tokens))
+	for i, t := range tokens {
+		if t == "" || strings.ContainsAny(t, " \t\n'\"\\$`|&;<>()*?[]#~") {
+			t = shellQuote(t)
+		}
+		quoted[i] = t
+	}
+	return prefix + strings.Join(quoted, " ")
+}
@@ -0,0 +1,45 @@
+package interp
+
+import "testing"
+
+func TestExpandWord_Nounset(t *testing.T) {
+	r := New()
+	r.SetOption("nounset", true)
+
+	if _, err := r.expandWord("$GSH_TEST_UNSET_VARIABLE", true); err == nil {
+		t.Errorf("expandWord should fail on an unset variable with nounset on")
+	}
+	if _, err := r.expandWord("$#\"$@\"", true); err != nil {
+		t.Errorf("expandWord returned error for special parameters: %v", err)
+	}
+}
+
+func TestTraceLine(t *testing.T) {
+	got := traceLine("+ ", []string{"echo", "a b", "it's", ""})
+	want := `+ echo 'a b' 'it'\''s' ''`
+	if got != want {
+		t.Errorf("traceLine = %q, want %q", got, want)
+	}
+}
+
+func TestBuiltinSet(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`set -o errexit; echo $-; set +o errexit; echo "<$->"`, "e\n<>\n"},
+		{`set -eu; echo $-; set +eu -x; echo $- 2>/dev/null`, "eu\nx\n"},
+		{`set -o menucomplete -o nounset; set -o | grep 'on$'`, "menucomplete   \ton\nnounset        \ton\n"},
+		{`set -o histexpand; set +o`, "set +o errexit\nset -o histexpand\nset +o menucomplete\nset +o nounset\nset +o xtrace\n"},
+		{`set -e; false; echo not reached`, ""},
+		{`set -o nosuch 2>&1; echo $?`, "set: nosuch: invalid option name\n1\n"},
+		{`set -z 2>/dev/null; echo $?`, "2\n"},
+		{`set -- a b; echo $# $2; set -x --; echo $# 2>/dev/null`, "2 b\n0\n"},
+		{`set -u c; echo $1`, "c\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
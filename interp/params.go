@@ -0,0 +1,92 @@
+package interp
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// scanParamName reads the parameter name following a '$' in s and returns it
+// along with the number of bytes consumed, or 0 if s doesn't start with one.
+// Names are a single special character or digit, an identifier, or any of
+// these wrapped in braces.
+func scanParamName(s string) (string, int) {
+	if s == "" {
+		return "", 0
+	}
+	if s[0] == '{' {
+		end := strings.IndexByte(s, '}')
+		if end <= 1 {
+			return "", 0
+		}
+		return s[1:end], end + 1
+	}
+	if strings.IndexByte("@*#?$-0123456789", s[0]) >= 0 {
+		return s[:1], 1
+	}
+	n := 0
+	for n < len(s) && (s[n] == '_' || isAlpha(s[n]) || (n > 0 && isDigit(s[n]))) {
+		n++
+	}
+	return s[:n], n
+}
+
+// lookupParam returns the value of a special or positional parameter, or of
+// the variable with that name, and whether it is set.
+func (r *Runner) lookupParam(name string) (string, bool) {
+	switch name {
+	case "0":
+		return r.Name, true
+	case "#":
+		return strconv.Itoa(len(r.Params)), true
+	case "?":
+		return strconv.Itoa(r.lastStatus), true
+	case "$":
+		return strconv.Itoa(os.Getpid()), true
+	case "-":
+		return r.optionFlags(), true
+	case "@", "*":
+		return strings.Join(r.Params, " "), true
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if n >= 1 && n <= len(r.Params) {
+			return r.Params[n-1], true
+		}
+		return "", false
+	}
+	if value, set, ok := r.lookupArrayParam(name); ok {
+		return value, set
+	}
+	if value, ok := r.vars[name]; ok {
+		return value, true
+	}
+	if value, ok := r.env[name]; ok {
+		return value, true
+	}
+	if arr, ok := r.arrays[name]; ok && len(arr) > 0 {
+		return arr[0], true
+	}
+	return "", false
+}
+
+// setVar assigns a variable. Exported variables stay exported, so commands
+// the shell starts see the new value.
+func (r *Runner) setVar(name, value string) {
+	if _, ok := r.arrays[name]; ok {
+		r.setArrayElement(name, 0, value)
+		return
+	}
+	if _, ok := r.env[name]; ok {
+		r.env[name] = value
+		return
+	}
+	r.vars[name] = value
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,240 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExpandPrompt expands the value of a prompt variable such as PS1. First the
+// bash-style backslash escapes are replaced:
+//
+//	\u  user name          \h  host name up to the first dot    \H  host name
+//	\w  working directory, with $HOME shown as ~                \W  its last element
+//	\$  # for root, else $ \?  status of the last command      \j  number of jobs
+//	\t  time as HH:MM:SS   \T  12-hour HH:MM:SS  \@  12-hour am/pm time  \A  HH:MM
+//	\d  date as "Tue May 26"                     \!  history number of the next command
+//	\s  shell name         \n  newline   \r  carriage return   \a  bell   \e  escape
+//	\g  git branch and status of the repository the working directory is in
+//	\nnn  octal byte       \\  backslash         \[ \]  ignored
+//
+// \[ and \] mark where non-printing sequences such as colors begin and end
+// in bash; readline measures the prompt without ANSI color sequences by
+// itself, so they are dropped. Then parameters, $(command) and `command`
+// substitutions and $((arithmetic)) are expanded as inside double quotes.
+func (r *Runner) ExpandPrompt(ps string) string {
+	// Commands run for a prompt aren't traced, which also keeps PS4 from
+	// being expanded for its own commands
+	if r.options["xtrace"] {
+		r.options["xtrace"] = false
+		defer func() { r.options["xtrace"] = true }()
+	}
+	return r.expandPromptVars(r.decodePrompt(ps))
+}
+
+// RunPromptCommand runs PROMPT_COMMAND, if it is set, as the shell does
+// before it shows PS1. The status of the last command is left as it was, so
+// that the prompt can show it.
+func (r *Runner) RunPromptCommand(ctx context.Context) {
+	pc, _ := r.lookupParam("PROMPT_COMMAND")
+	if strings.TrimSpace(pc) == "" {
+		return
+	}
+	status := r.lastStatus
+	r.RunString(ctx, pc)
+	r.lastStatus = status
+}
+
+// decodePrompt replaces the backslash escapes in ps. Text they insert is
+// escaped so that it isn't expanded again by expandPromptVars.
+func (r *Runner) decodePrompt(ps string) string {
+	var b strings.Builder
+	now := time.Now()
+	for i := 0; i < len(ps); i++ {
+		if ps[i] != '\\' || i+1 == len(ps) {
+			b.WriteByte(ps[i])
+			continue
+		}
+		i++
+		var s string
+		switch c := ps[i]; c {
+		case 'u':
+			s = promptUser(r)
+		case 'h', 'H':
+			s, _ = os.Hostname()
+			if c == 'h' {
+				s, _, _ = strings.Cut(s, ".")
+			}
+		case 'w', 'W':
+			s = r.Dir
+			home, _ := r.lookupParam("HOME")
+			switch {
+			case home != "" && s == home:
+				s = "~"
+			case c == 'W' && s != "/":
+				s = filepath.Base(s)
+			case home != "" && strings.HasPrefix(s, strings.TrimSuffix(home, "/")+"/"):
+				s = "~" + s[len(strings.TrimSuffix(home, "/")):]
+			}
+		case '$':
+			// Escaped so that it isn't taken for a parameter expansion
+			s = "\\$"
+			if os.Geteuid() == 0 {
+				s = "#"
+			}
+			b.WriteString(s)
+			continue
+		case '?':
+			s = strconv.Itoa(r.lastStatus)
+		case 'j':
+			r.jobsMu.Lock()
+			s = strconv.Itoa(len(r.jobs))
+			r.jobsMu.Unlock()
+		case 't':
+			s = now.Format("15:04:05")
+		case 'T':
+			s = now.Format("03:04:05")
+		case '@':
+			s = now.Format("03:04 PM")
+		case 'A':
+			s = now.Format("15:04")
+		case 'd':
+			s = now.Format("Mon Jan 02")
+		case '!':
+			s = strconv.Itoa(len(r.history) + 1)
+		case 's':
+			s = filepath.Base(r.Name)
+		case 'g':
+			s = r.gitPrompt()
+		case 'n':
+			s = "\n"
+		case 'r':
+			s = "\r"
+		case 'a':
+			s = "\a"
+		case 'e':
+			s = "\033"
+		case '[', ']':
+		case '\\':
+			s = "\\"
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := i + 1
+			for end < len(ps) && end < i+3 && ps[end] >= '0' && ps[end] <= '7' {
+				end++
+			}
+			n, _ := strconv.ParseUint(ps[i:end], 8, 8)
+			s = string([]byte{byte(n)})
+			i = end - 1
+		default:
+			s = ps[i-1 : i+1]
+		}
+		b.WriteString(escapeForPrompt(s))
+	}
+	return b.String()
+}
+
+// escapeForPrompt escapes the characters expandPromptVars treats specially.
+func escapeForPrompt(s string) string {
+	if !strings.ContainsAny(s, "\\$`") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte("\\$`", s[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// promptUser returns the name of the user running the shell.
+func promptUser(r *Runner) string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	name, _ := r.lookupParam("USER")
+	return name
+}
+
+// expandPromptVars expands parameters, command substitutions and arithmetic
+// in s as inside double quotes, but leaves quote characters in place.
+// Expansion errors are reported on Stderr and leave the text as written.
+func (r *Runner) expandPromptVars(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\$`\"", s[i+1]) >= 0:
+			i++
+			b.WriteByte(s[i])
+		case c == '`':
+			end := strings.IndexByte(s[i+1:], '`')
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			b.WriteString(r.commandOutput(s[i+1 : i+1+end]))
+			i += end + 1
+		case c == '$' && strings.HasPrefix(s[i:], "$(("):
+			end := matchingParen(s, i+3, 2)
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			v, err := r.evalArith(s[i+3 : end-1])
+			if err != nil {
+				fmt.Fprintf(r.Stderr, "%s: %v\n", r.Name, err)
+				b.WriteString(s[i : end+1])
+			} else {
+				b.WriteString(strconv.FormatInt(v, 10))
+			}
+			i = end
+		case c == '$' && strings.HasPrefix(s[i:], "$("):
+			end := matchingParen(s, i+2, 1)
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			b.WriteString(r.commandOutput(s[i+2 : end]))
+			i = end
+		case c == '$':
+			name, n := scanParamName(s[i+1:])
+			if n == 0 {
+				b.WriteByte(c)
+				continue
+			}
+			value, _ := r.lookupParam(name)
+			b.WriteString(value)
+			i += n
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// tracePrefix returns the expansion of PS4, which starts each line printed
+// by the xtrace option. It is "+ " when PS4 is unset.
+func (r *Runner) tracePrefix() string {
+	ps4, ok := r.lookupParam("PS4")
+	if !ok {
+		return "+ "
+	}
+	return r.ExpandPrompt(ps4)
+}
+
+// gitPrompt returns the text of the \g prompt escape: the status of the git
+// repository the working directory is in, or "" if it isn't in one.
+func (r *Runner) gitPrompt() string {
+	status, ok := r.gitCache.Status(r.Dir)
+	if !ok {
+		return ""
+	}
+	return status.String()
+}
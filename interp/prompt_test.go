@@ -0,0 +1,107 @@
+package interp
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandPrompt(t *testing.T) {
+	r := New()
+	home := t.TempDir()
+	r.SetVar("HOME", home)
+	r.SetVar("NAME", "world")
+	r.Dir = filepath.Join(home, "src", "gsh")
+	r.Name = "/bin/gsh"
+	r.lastStatus = 3
+	r.AddHistory("true")
+
+	dollar := "$"
+	if os.Geteuid() == 0 {
+		dollar = "#"
+	}
+	tests := []struct {
+		ps   string
+		want string
+	}{
+		{`plain> `, "plain> "},
+		{`\w\$ `, "~/src/gsh" + dollar + " "},
+		{`\W `, "gsh "},
+		{`[\?] `, "[3] "},
+		{`\j \!`, "0 2"},
+		{`\s`, "gsh"},
+		{`a\nb`, "a\nb"},
+		{`\[\e[1;32m\]ok\[\e[0m\]`, "\033[1;32mok\033[0m"},
+		{`\101\\`, "A\\"},
+		{`\q`, `\q`},
+		{`hello $NAME ${NAME}!`, "hello world world!"},
+		{`$(echo sub) ` + "`echo back`", "sub back"},
+		{`$(echo "(a)")`, "(a)"},
+		{`$((6 * 7))`, "42"},
+		{`\$NAME "q"`, dollar + `NAME "q"`},
+		{`\\$NAME`, `\world`},
+	}
+	for _, tt := range tests {
+		if got := r.ExpandPrompt(tt.ps); got != tt.want {
+			t.Errorf("ExpandPrompt(%q) = %q, want %q", tt.ps, got, tt.want)
+		}
+	}
+
+	r.Dir = home
+	if got := r.ExpandPrompt(`\w \W`); got != "~ ~" {
+		t.Errorf("ExpandPrompt in $HOME = %q, want %q", got, "~ ~")
+	}
+}
+
+func TestRunPromptCommand(t *testing.T) {
+	r := New()
+	runIn(t, r, "PROMPT_COMMAND='count=${count}x'; false")
+	r.RunPromptCommand(context.Background())
+	r.RunPromptCommand(context.Background())
+	if got := r.ExpandPrompt(`$count \?`); got != "xx 1" {
+		t.Errorf("prompt after PROMPT_COMMAND = %q, want %q", got, "xx 1")
+	}
+}
+
+func TestTracePrefix(t *testing.T) {
+	r := New()
+	f, err := os.CreateTemp(t.TempDir(), "err")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r.Stderr = f
+	r.Name = "gsh"
+	r.SetOption("xtrace", true)
+	runIn(t, r, "echo one; PS4='[$LINENO_X] '; echo two; PS4='\\s> '; echo three")
+
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "+ echo one\n[] echo two\ngsh> echo three\n"
+	if !strings.HasSuffix(string(got), want) {
+		t.Errorf("xtrace output = %q, want %q", got, want)
+	}
+}
+
+func TestExpandPrompt_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := New()
+	r.Dir = t.TempDir()
+	if got := r.ExpandPrompt(`[\g]`); got != "[]" {
+		t.Errorf(`\g outside a repository = %q, want ""`, got)
+	}
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", r.Dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	os.WriteFile(filepath.Join(r.Dir, "new"), nil, 0644)
+	if got := r.ExpandPrompt(`[\g]`); got != "[main %]" {
+		t.Errorf(`\g = %q, want "main %%"`, got)
+	}
+}
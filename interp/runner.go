@@ -0,0 +1,362 @@
+// Package interp runs shell programs parsed by the syntax package.
+package interp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KayaLuken/golang-shell/internal/gitstatus"
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+// Runner holds the state of a shell: its variables, functions, options,
+// traps and background jobs, along with the directory and streams its
+// commands run with. A Runner must only be used by one goroutine at a time,
+// but separate Runners are independent and may run concurrently.
+type Runner struct {
+	// Dir is the working directory. Commands are started in it and relative
+	// paths are resolved against it; the process's own working directory is
+	// never changed.
+	Dir string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Name is $0: the script being run, or the shell itself.
+	Name string
+	// Params holds the positional parameters $1, $2, ...
+	Params []string
+	// Interactive is set when commands are read from a terminal. It makes
+	// the shell report background jobs and keep going after errors that
+	// would end a script.
+	Interactive bool
+	// KillTimeout is how long a command is given to exit after it is sent
+	// SIGTERM because its context is done, before it is sent SIGKILL. If it
+	// is zero or negative, SIGKILL is sent right away.
+	KillTimeout time.Duration
+
+	// ctx is the context of the running Run call. When it is done, running
+	// commands are killed and no further commands are started.
+	ctx context.Context
+
+	// env holds the exported variables, which commands the shell starts
+	// inherit; vars holds the ones that aren't exported.
+	env  map[string]string
+	vars map[string]string
+	// arrays holds the indexed arrays.
+	arrays map[string][]string
+
+	options   map[string]bool
+	functions map[string]*syntax.FuncDef
+	// builtins holds the builtins registered with this Runner, starting out
+	// as a copy of defaultBuiltins.
+	builtins map[string]Builtin
+	// aliases maps an alias name to its value, which replaces the name when
+	// it starts a command.
+	aliases map[string]string
+
+	// lastStatus is $?, the exit status of the most recent command.
+	lastStatus int
+
+	// traps maps a trap condition to its action. Real signals are keyed by
+	// their name without the SIG prefix, pseudo-signals by their own name.
+	// An empty action means the signal is ignored.
+	traps map[string]string
+	// signals receives the trapped signals. Their actions run once the
+	// foreground command running when the signal arrived has finished, so
+	// they never interleave with it.
+	signals chan os.Signal
+	inTrap  bool // set while a DEBUG, ERR, RETURN or signal trap is running
+	exiting bool // set once the EXIT trap has started
+
+	// noErrExit is non-zero while running commands whose failure must not
+	// trigger the ERR trap or the errexit option: if conditions, all but the
+	// last pipeline of an and-or list, and negated pipelines.
+	noErrExit int
+
+	// loopDepth is the number of loops currently running. breakLevels and
+	// continueLevels count the enclosing loops a break or continue still has
+	// to unwind.
+	loopDepth      int
+	breakLevels    int
+	continueLevels int
+
+	// funcDepth is the number of function calls currently running, and
+	// sourceDepth the number of files being run by source.
+	funcDepth   int
+	sourceDepth int
+	// localScopes holds, for each running function call, the values that
+	// its local declarations hid, to be restored when it returns.
+	localScopes []map[string]savedVar
+	// returning is set by return until the function call it ends has
+	// unwound; returnStatus is the status it returns with.
+	returning    bool
+	returnStatus int
+
+	// exited is set once exit has run; the commands still running unwind and
+	// the shell finishes with exitCode.
+	exited   bool
+	exitCode int
+
+	// history holds the command lines entered, oldest first. Those from
+	// histAppended on haven't been written to the history file yet.
+	history      []histEntry
+	histAppended int
+	// histSubst is the text the last :s history modifier replaced.
+	histSubst string
+	// compSpecs holds the completion specs set with complete, by command
+	// name.
+	compSpecs map[string]*compSpec
+	// gitCache holds what the \g prompt escape found out about
+	// repositories.
+	gitCache *gitstatus.Cache
+
+	jobsMu sync.Mutex
+	jobs   []*job // in launch order; the last entry is the current job
+	// group is the process group of the background job the Runner runs
+	// in, if any, which the external commands it starts join.
+	group *procGroup
+}
+
+// ExitStatus is the error returned for a non-zero exit status.
+type ExitStatus uint8
+
+func (s ExitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", uint8(s))
+}
+
+// New returns a Runner using the process's environment, working directory
+// and standard streams, which gives commands two seconds to exit once they
+// are sent SIGTERM.
+func New() *Runner {
+	dir, _ := os.Getwd()
+	r := &Runner{
+		Dir:         dir,
+		Stdin:       os.Stdin,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+		Name:        os.Args[0],
+		KillTimeout: 2 * time.Second,
+		ctx:         context.Background(),
+		vars:        make(map[string]string),
+		arrays:      make(map[string][]string),
+		options:     make(map[string]bool),
+		functions:   make(map[string]*syntax.FuncDef),
+		builtins:    maps.Clone(defaultBuiltins),
+		aliases:     make(map[string]string),
+		traps:       make(map[string]string),
+		signals:     make(chan os.Signal, 16),
+		compSpecs:   make(map[string]*compSpec),
+		gitCache:    gitstatus.NewCache(),
+	}
+	for name := range optionNames {
+		r.options[name] = false
+	}
+	r.SetEnv(os.Environ())
+	return r
+}
+
+// SetEnv replaces the exported variables with env, given as NAME=value
+// entries.
+func (r *Runner) SetEnv(env []string) {
+	r.env = make(map[string]string, len(env))
+	for _, kv := range env {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			r.env[name] = value
+		}
+	}
+}
+
+// Environ returns the exported variables as sorted NAME=value entries, as
+// passed to the commands the shell starts.
+func (r *Runner) Environ() []string {
+	env := make([]string, 0, len(r.env))
+	for name, value := range r.env {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// Run runs prog. It returns an ExitStatus if the status of its last command
+// is non-zero, or the context's error if ctx is done before it finishes. The
+// external commands it started are then killed along with their process
+// groups: see KillTimeout.
+func (r *Runner) Run(ctx context.Context, prog *syntax.List) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	saved := r.ctx
+	r.ctx = ctx
+	defer func() { r.ctx = saved }()
+
+	r.lastStatus = r.runList(prog, r.stdio())
+	r.runPendingTraps()
+	if r.Interactive {
+		r.reportDoneJobs(r.Stderr)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return statusErr(r.lastStatus)
+}
+
+// RunString parses and runs src. A syntax error is reported on Stderr and
+// gives the status 2.
+func (r *Runner) RunString(ctx context.Context, src string) error {
+	prog, err := r.parse(src)
+	if err != nil {
+		fmt.Fprintf(r.Stderr, "%s: %v\n", r.Name, err)
+		r.lastStatus = 2
+		return statusErr(2)
+	}
+	return r.Run(ctx, prog)
+}
+
+// RunReader reads commands from rd and runs each one as soon as it is
+// complete. A leading shebang line is skipped like any other comment. A
+// syntax error, or exit, stops reading.
+func (r *Runner) RunReader(ctx context.Context, rd io.Reader) error {
+	var runErr error
+	err := r.readCommands(rd, func(prog *syntax.List) bool {
+		runErr = r.Run(ctx, prog)
+		return !r.exited && (runErr == nil || ctx.Err() == nil)
+	})
+	if err != nil {
+		fmt.Fprintf(r.Stderr, "%s: %v\n", r.Name, err)
+		r.lastStatus = 2
+		return statusErr(2)
+	}
+	return runErr
+}
+
+// readCommands reads rd a line at a time and passes each complete command to
+// run, so that aliases it defines apply to the commands after it. It stops at
+// the end of rd or once run returns false, and returns any syntax error along
+// with the number of the line it was found on.
+func (r *Runner) readCommands(rd io.Reader, run func(prog *syntax.List) bool) error {
+	br := bufio.NewReader(rd)
+	var src strings.Builder
+	for lineNum := 1; ; lineNum++ {
+		line, err := br.ReadString('\n')
+		src.WriteString(strings.TrimRight(line, "\r\n") + "\n")
+		prog, perr := r.parse(src.String())
+		if perr == syntax.ErrIncomplete && err == nil {
+			continue
+		}
+		src.Reset()
+		if perr != nil {
+			return fmt.Errorf("line %d: %w", lineNum, perr)
+		}
+		if !run(prog) || err != nil {
+			return nil
+		}
+	}
+}
+
+// Exited reports whether the shell has run exit, or stopped because of the
+// errexit option.
+func (r *Runner) Exited() bool {
+	return r.exited
+}
+
+// Exit runs the EXIT trap, if any, and returns the status the shell
+// finishes with: the one given to exit, or else that of the last command.
+func (r *Runner) Exit() int {
+	status := r.lastStatus
+	if r.exited {
+		status = r.exitCode
+	}
+	if action := r.traps["EXIT"]; action != "" && !r.exiting {
+		r.exiting = true
+		r.exited = false
+		r.lastStatus = status
+		r.runCommandLine(action)
+		if r.exited {
+			status = r.exitCode
+		}
+	}
+	r.exited, r.exitCode = true, status
+	return status
+}
+
+// Status returns $?, the exit status of the most recent command.
+func (r *Runner) Status() int {
+	return r.lastStatus
+}
+
+// LookupVar returns the value of a variable or special parameter, and
+// whether it is set.
+func (r *Runner) LookupVar(name string) (string, bool) {
+	return r.lookupParam(name)
+}
+
+// SetVar sets the variable name to value, keeping it exported if it is.
+func (r *Runner) SetVar(name, value string) {
+	r.setVar(name, value)
+}
+
+// exit makes the shell finish with status once the running commands have
+// unwound.
+func (r *Runner) exit(status int) {
+	r.exited, r.exitCode = true, status
+}
+
+// subshell returns a copy of r to run a subshell or a pipeline stage in.
+// Changes it makes to variables, functions, options, traps or the directory
+// don't affect r, as if the shell had forked.
+func (r *Runner) subshell() *Runner {
+	sub := &Runner{
+		Dir:         r.Dir,
+		Stdin:       r.Stdin,
+		Stdout:      r.Stdout,
+		Stderr:      r.Stderr,
+		Name:        r.Name,
+		Params:      r.Params,
+		Interactive: r.Interactive,
+		KillTimeout: r.KillTimeout,
+		ctx:         r.ctx,
+		env:         maps.Clone(r.env),
+		vars:        maps.Clone(r.vars),
+		arrays:      maps.Clone(r.arrays),
+		options:     maps.Clone(r.options),
+		functions:   maps.Clone(r.functions),
+		builtins:    maps.Clone(r.builtins),
+		aliases:     maps.Clone(r.aliases),
+		history:     slices.Clone(r.history),
+		histSubst:   r.histSubst,
+		compSpecs:   maps.Clone(r.compSpecs),
+		gitCache:    r.gitCache,
+		group:       r.group,
+		lastStatus:  r.lastStatus,
+		traps:       make(map[string]string),
+		signals:     make(chan os.Signal, 16),
+		noErrExit:   r.noErrExit,
+		funcDepth:   r.funcDepth,
+		sourceDepth: r.sourceDepth,
+		localScopes: make([]map[string]savedVar, len(r.localScopes)),
+	}
+	// A subshell starts without the shell's traps, except for ignored
+	// signals, and catches its trapped signals on its own channel
+	for cond, action := range r.traps {
+		if action == "" {
+			sub.traps[cond] = ""
+		}
+	}
+	return sub
+}
+
+// stdio returns the Runner's standard streams.
+func (r *Runner) stdio() stdio {
+	return stdio{r.Stdin, r.Stdout, r.Stderr}
+}
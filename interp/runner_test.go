@@ -0,0 +1,137 @@
+package interp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunReader_ReturnsLastStatus(t *testing.T) {
+	r := New()
+	if err := r.RunReader(context.Background(), strings.NewReader("true\nfalse\n")); err != ExitStatus(1) {
+		t.Errorf("RunReader error = %v, want exit status 1", err)
+	}
+	if err := r.RunReader(context.Background(), strings.NewReader("false\ntrue")); err != nil {
+		t.Errorf("RunReader error = %v, want nil", err)
+	}
+}
+
+func TestRunner_Streams(t *testing.T) {
+	var out, errOut bytes.Buffer
+	r := New()
+	r.Stdin = strings.NewReader("from stdin\n")
+	r.Stdout, r.Stderr = &out, &errOut
+
+	err := r.RunString(context.Background(), "read line; echo \"$line\"; echo err >&2; echo x | tr a-z A-Z")
+	if err != nil {
+		t.Fatalf("RunString returned error: %v", err)
+	}
+	if out.String() != "from stdin\nX\n" || errOut.String() != "err\n" {
+		t.Errorf("stdout = %q, stderr = %q", out.String(), errOut.String())
+	}
+}
+
+func TestRunner_EnvAndDir(t *testing.T) {
+	var out bytes.Buffer
+	dir := t.TempDir()
+	r := New()
+	r.Dir = dir
+	r.SetEnv([]string{"PATH=/usr/bin:/bin", "GSH_GREETING=hi"})
+	r.Stdout = &out
+
+	r.RunString(context.Background(), "pwd; echo $GSH_GREETING; sh -c 'echo $GSH_GREETING'; GSH_GREETING=bye; env | grep GSH_")
+	if want := dir + "\nhi\nhi\nGSH_GREETING=bye\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunner_Independent(t *testing.T) {
+	a, b := New(), New()
+	var out bytes.Buffer
+	a.RunString(context.Background(), "x=1; f() { echo f; }; cd /")
+	b.Stdout = &out
+	b.RunString(context.Background(), "echo \"[$x]\"; type f")
+	if want := "[]\nf: not found\n"; out.String() != want {
+		t.Errorf("second runner printed %q, want %q", out.String(), want)
+	}
+	if a.Dir != "/" || b.Dir == "/" {
+		t.Errorf("Dir = %q and %q, want only the first runner in /", a.Dir, b.Dir)
+	}
+}
+
+func TestRunner_SyntaxError(t *testing.T) {
+	var errOut bytes.Buffer
+	r := New()
+	r.Name = "gsh"
+	r.Stderr = &errOut
+	if err := r.RunString(context.Background(), "fi"); err != ExitStatus(2) {
+		t.Errorf("RunString error = %v, want exit status 2", err)
+	}
+	if want := "gsh: syntax error near unexpected token `fi'\n"; errOut.String() != want {
+		t.Errorf("stderr = %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestRunner_SyntaxErrorLine(t *testing.T) {
+	var out, errOut bytes.Buffer
+	r := New()
+	r.Name = "script.sh"
+	r.Stdout, r.Stderr = &out, &errOut
+	r.RunReader(context.Background(), strings.NewReader("echo a\nif true\nthen fi\necho b\n"))
+	if out.String() != "a\n" {
+		t.Errorf("stdout = %q, want %q", out.String(), "a\n")
+	}
+	if want := "script.sh: line 3: syntax error near unexpected token `fi'\n"; errOut.String() != want {
+		t.Errorf("stderr = %q, want %q", errOut.String(), want)
+	}
+}
+
+func TestRunner_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := New().RunString(ctx, "echo no"); err != context.Canceled {
+		t.Errorf("RunString error = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunner_CancelKillsProcessGroup(t *testing.T) {
+	// The command ignores SIGTERM and leaves a child behind, so both have to
+	// be killed with SIGKILL through the process group. In a pipeline, the
+	// group is led by the first stage.
+	for _, pipe := range []string{"", " | cat"} {
+		r := New()
+		r.Stdin = nil
+		r.KillTimeout = 100 * time.Millisecond
+		pidFile := filepath.Join(t.TempDir(), "pid")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		start := time.Now()
+		err := r.RunString(ctx, "true | true; sh -c 'trap \"\" TERM; sleep 30 & echo $! > "+pidFile+"; wait'"+pipe+"; echo not reached")
+		cancel()
+		if err != context.DeadlineExceeded {
+			t.Errorf("RunString error = %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("RunString took %v after cancellation", elapsed)
+		}
+
+		data, err := os.ReadFile(pidFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		deadline := time.Now().Add(5 * time.Second)
+		for syscall.Kill(pid, 0) == nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("child process %d of %q survived cancellation", pid, pipe)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
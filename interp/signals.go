@@ -0,0 +1,75 @@
+package interp
+
+import (
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// signalNames lists the signals known to the shell by their name without the
+// SIG prefix, in ascending signal number order.
+var signalNames = []struct {
+	name string
+	sig  syscall.Signal
+}{
+	{"HUP", syscall.SIGHUP},
+	{"INT", syscall.SIGINT},
+	{"QUIT", syscall.SIGQUIT},
+	{"ILL", syscall.SIGILL},
+	{"TRAP", syscall.SIGTRAP},
+	{"ABRT", syscall.SIGABRT},
+	{"BUS", syscall.SIGBUS},
+	{"FPE", syscall.SIGFPE},
+	{"KILL", syscall.SIGKILL},
+	{"USR1", syscall.SIGUSR1},
+	{"SEGV", syscall.SIGSEGV},
+	{"USR2", syscall.SIGUSR2},
+	{"PIPE", syscall.SIGPIPE},
+	{"ALRM", syscall.SIGALRM},
+	{"TERM", syscall.SIGTERM},
+	{"CHLD", syscall.SIGCHLD},
+	{"CONT", syscall.SIGCONT},
+	{"STOP", syscall.SIGSTOP},
+	{"TSTP", syscall.SIGTSTP},
+	{"TTIN", syscall.SIGTTIN},
+	{"TTOU", syscall.SIGTTOU},
+	{"URG", syscall.SIGURG},
+	{"XCPU", syscall.SIGXCPU},
+	{"XFSZ", syscall.SIGXFSZ},
+	{"VTALRM", syscall.SIGVTALRM},
+	{"PROF", syscall.SIGPROF},
+	{"WINCH", syscall.SIGWINCH},
+	{"IO", syscall.SIGIO},
+	{"SYS", syscall.SIGSYS},
+}
+
+// lookupSignal translates a signal name (with or without the SIG prefix, in
+// any case) or number into a signal.
+func lookupSignal(spec string) (syscall.Signal, bool) {
+	if n, err := strconv.Atoi(spec); err == nil {
+		for _, s := range signalNames {
+			if int(s.sig) == n {
+				return s.sig, true
+			}
+		}
+		return 0, false
+	}
+	name := strings.TrimPrefix(strings.ToUpper(spec), "SIG")
+	for _, s := range signalNames {
+		if s.name == name {
+			return s.sig, true
+		}
+	}
+	return 0, false
+}
+
+// signalName returns the name of sig without the SIG prefix, or its number if
+// the signal is unknown.
+func signalName(sig syscall.Signal) string {
+	for _, s := range signalNames {
+		if s.sig == sig {
+			return s.name
+		}
+	}
+	return strconv.Itoa(int(sig))
+}
@@ -0,0 +1,116 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+func init() {
+	source := BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		if len(c.Args) < 2 {
+			fmt.Fprintf(c.Stderr, "%s: filename argument required\n", c.Args[0])
+			fmt.Fprintf(c.Stderr, "%s: usage: %s filename [arguments]\n", c.Args[0], c.Args[0])
+			return 2
+		}
+		path := r.findSourceFile(c.Args[1])
+		f, err := os.Open(r.resolvePath(path))
+		if err != nil {
+			fmt.Fprintf(c.Stderr, "%s: %s: %v\n", c.Args[0], c.Args[1], unwrapPathError(err))
+			return 1
+		}
+		defer f.Close()
+		return r.runSource(f, c.Args[1], c.Args[2:], stdio{c.Stdin, c.Stdout, c.Stderr})
+	})
+	defaultBuiltins["source"] = source
+	defaultBuiltins["."] = source
+}
+
+// Source runs the commands in the file at path in the shell, as the source
+// builtin does. Errors opening the file are returned rather than reported, so
+// that a missing startup file can be skipped quietly; syntax errors are
+// reported on Stderr with the file name and line. Like Run, it returns the
+// context's error if it is done.
+func (r *Runner) Source(ctx context.Context, path string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	saved := r.ctx
+	r.ctx = ctx
+	defer func() { r.ctx = saved }()
+
+	f, err := os.Open(r.resolvePath(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r.lastStatus = r.runSource(f, path, nil, r.stdio())
+	r.runPendingTraps()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return statusErr(r.lastStatus)
+}
+
+// findSourceFile returns the file source runs for name: name itself if it
+// contains a slash, else the first readable file of that name in PATH, or
+// name in the working directory if there is none.
+func (r *Runner) findSourceFile(name string) string {
+	if strings.ContainsRune(name, os.PathSeparator) {
+		return name
+	}
+	pathEnv, _ := r.lookupParam("PATH")
+	for _, dir := range strings.Split(pathEnv, string(os.PathListSeparator)) {
+		if dir == "" {
+			continue
+		}
+		path := dir + string(os.PathSeparator) + name
+		if info, err := os.Stat(r.resolvePath(path)); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	return name
+}
+
+// runSource runs the commands read from f in the current shell, with args as
+// the positional parameters if there are any. return ends the file early.
+func (r *Runner) runSource(f *os.File, name string, args []string, st stdio) int {
+	// Like function calls, nested sources are limited so that a file that
+	// sources itself fails instead of exhausting the stack
+	if r.sourceDepth >= maxFuncDepth {
+		fmt.Fprintf(st.err, "%s: %s: maximum source nesting level exceeded (%d)\n", r.Name, name, maxFuncDepth)
+		return 1
+	}
+	savedParams := r.Params
+	if len(args) > 0 {
+		r.Params = args
+	}
+	r.sourceDepth++
+	defer func() {
+		r.sourceDepth--
+		if len(args) > 0 {
+			r.Params = savedParams
+		}
+	}()
+
+	status := r.lastStatus
+	err := r.readCommands(f, func(prog *syntax.List) bool {
+		status = r.runList(prog, st)
+		return !r.interrupted()
+	})
+	if err != nil {
+		fmt.Fprintf(st.err, "%s: %s: %v\n", r.Name, name, err)
+		status = 2
+	}
+	if r.returning {
+		r.returning = false
+		status = r.returnStatus
+	}
+	r.lastStatus = status
+	r.runTrap("RETURN")
+	return status
+}
@@ -0,0 +1,73 @@
+package interp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	env := write("env.sh", "GSH_TEST_SOURCED=yes\ngreet() { echo hello $1; }\n")
+	args := write("args.sh", "echo \"$# $1 $2\"\n")
+	early := write("early.sh", "echo before\nreturn 4\necho after\n")
+	alias := write("alias.sh", "alias hi='echo hi'\nhi there\n")
+	write("onpath.sh", "echo found on PATH\n")
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"source " + env + "; echo $GSH_TEST_SOURCED; greet you", "yes\nhello you\n"},
+		{". " + env + "; echo $GSH_TEST_SOURCED", "yes\n"},
+		{"f() { source " + args + " a b; echo $#; }; f x", "2 a b\n1\n"},
+		{"f() { source " + args + "; }; f x", "1 x \n"},
+		{"source " + early + "; echo $?", "before\n4\n"},
+		{"f() { source " + early + "; echo in f $?; }; f", "before\nin f 4\n"},
+		{"source " + alias, "hi there\n"},
+		{"PATH=" + dir + "; source onpath.sh", "found on PATH\n"},
+		{"cd " + dir + "; source args.sh a", "1 a \n"},
+		{"source " + args + " a > " + filepath.Join(dir, "out") + "; cat " + filepath.Join(dir, "out"), "1 a \n"},
+		{"trap 'echo returned' RETURN; source " + early, "before\nreturned\n"},
+		{"source " + filepath.Join(dir, "missing") + " 2>/dev/null; echo $?", "1\n"},
+		{"source 2>/dev/null; echo $?", "2\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSource_Errors(t *testing.T) {
+	dir := t.TempDir()
+	self := filepath.Join(dir, "self.sh")
+	os.WriteFile(self, []byte("source "+self+"\n"), 0644)
+	bad := filepath.Join(dir, "bad.sh")
+	os.WriteFile(bad, []byte("echo ok\nfi\necho not run\n"), 0644)
+
+	var stderr strings.Builder
+	r := New()
+	r.Stderr = &stderr
+	if got := runIn(t, r, "source "+self+"; echo $?"); got != "1\n" {
+		t.Errorf("recursive source printed %q, want status 1", got)
+	}
+	if !strings.Contains(stderr.String(), "maximum source nesting level exceeded") {
+		t.Errorf("recursive source stderr = %q", stderr.String())
+	}
+	stderr.Reset()
+	if got := runIn(t, r, "source "+bad+"; echo $?"); got != "ok\n2\n" {
+		t.Errorf("source with a syntax error printed %q, want %q", got, "ok\n2\n")
+	}
+	if !strings.Contains(stderr.String(), "syntax error near unexpected token `fi'") {
+		t.Errorf("source with a syntax error: stderr = %q", stderr.String())
+	}
+}
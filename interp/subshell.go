@@ -0,0 +1,42 @@
+package interp
+
+import (
+	"os/signal"
+
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+// runSubshell runs c.body in a copy of the shell, so that changes it makes to
+// the working directory, variables, functions, options and traps don't
+// outlive it. exit and return in the body only end the subshell.
+func (r *Runner) runSubshell(c *syntax.Subshell, st stdio) int {
+	sub := r.subshell()
+	defer r.restoreSignals(sub)
+	status := sub.runList(c.Body, st)
+	if sub.returning {
+		status = sub.returnStatus
+	}
+	sub.lastStatus = status
+	return sub.Exit()
+}
+
+// restoreSignals reinstalls the signal dispositions of r's traps that sub
+// changed. Dispositions belong to the process, so a subshell that sets or
+// resets a trap changes them for its parent too.
+func (r *Runner) restoreSignals(sub *Runner) {
+	signal.Stop(sub.signals)
+	for cond := range sub.traps {
+		if _, ok := r.traps[cond]; !ok {
+			if _, sig, _ := parseTrapCondition(cond); sig != 0 {
+				r.setTrap(cond, sig, "-")
+			}
+		}
+	}
+	for cond, action := range r.traps {
+		if current, ok := sub.traps[cond]; !ok || current != action {
+			if _, sig, _ := parseTrapCondition(cond); sig != 0 {
+				r.setTrap(cond, sig, action)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package interp
+
+import (
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestRunSubshell_Isolated(t *testing.T) {
+	r := New()
+	wd := r.Dir
+	dir := t.TempDir()
+
+	got := runIn(t, r, "gsh_test_x=1; (cd "+dir+" && pwd; gsh_test_x=2; gsh_test_f() { :; }; echo $gsh_test_x); pwd; echo $gsh_test_x")
+	if want := dir + "\n2\n" + wd + "\n1\n"; got != want {
+		t.Errorf("subshell printed %q, want %q", got, want)
+	}
+	if _, ok := r.functions["gsh_test_f"]; ok {
+		t.Errorf("function defined in a subshell leaked into the shell")
+	}
+}
+
+func TestRunGroups(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "log")
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"(exit 3); echo $?", "3\n"},
+		{"(echo a; exit 4; echo b); echo $?", "a\n4\n"},
+		{"f() { (return 5; echo no); echo $?; }; f", "5\n"},
+		{"(trap 'echo trapped' EXIT; echo body); echo after", "body\ntrapped\nafter\n"},
+		{"gsh_test_x=0; { gsh_test_x=9; }; echo $gsh_test_x", "9\n"},
+		{"{ echo a; echo b; } > " + out + "; cat " + out, "a\nb\n"},
+		{"(echo a; echo b) | tr a-z A-Z", "A\nB\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRunSubshell_SignalsRestored(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+		died syscall.Signal
+	}{
+		{"(trap '' TERM); kill -TERM $$; echo survived", "", syscall.SIGTERM},
+		{"(trap '' HUP); kill -HUP $$; echo survived", "", syscall.SIGHUP},
+		{"x=$(trap '' TERM); kill -TERM $$; echo survived", "", syscall.SIGTERM},
+		{"trap '' TERM; (trap - TERM); kill -TERM $$; echo survived", "survived\n", 0},
+		{"trap 'echo got' TERM; (trap '' TERM); sh -c 'kill -TERM $PPID'; sleep 0.1; echo after", "got\nafter\n", 0},
+	}
+	for _, tt := range tests {
+		if tt.died != 0 && signal.Ignored(tt.died) {
+			continue
+		}
+		if got, died := runShellProcess(t, tt.src); got != tt.want || died != tt.died {
+			t.Errorf("%q printed %q and died of %v, want %q and %v", tt.src, got, died, tt.want, tt.died)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package interp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	defaultBuiltins["timeout"] = BuiltinFunc(func(c *Call) int {
+		r, args := c.Runner, c.Args
+		args = args[1:]
+		killAfter := r.KillTimeout
+	options:
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+			switch args[0] {
+			case "--":
+				args = args[1:]
+				break options
+			case "-k":
+				if len(args) < 2 {
+					fmt.Fprintln(c.Stderr, "timeout: -k: option requires an argument")
+					return 125
+				}
+				d, ok := parseDuration(args[1])
+				if !ok {
+					fmt.Fprintf(c.Stderr, "timeout: invalid time interval '%s'\n", args[1])
+					return 125
+				}
+				killAfter, args = d, args[2:]
+			default:
+				fmt.Fprintf(c.Stderr, "timeout: %s: invalid option\n", args[0])
+				return 125
+			}
+		}
+		if len(args) < 2 {
+			fmt.Fprintln(c.Stderr, "timeout: usage: timeout [-k duration] duration command [arg ...]")
+			return 125
+		}
+		limit, ok := parseDuration(args[0])
+		if !ok {
+			fmt.Fprintf(c.Stderr, "timeout: invalid time interval '%s'\n", args[0])
+			return 125
+		}
+		return r.runTimeout(limit, killAfter, args[1:], stdio{c.Stdin, c.Stdout, c.Stderr})
+	})
+}
+
+// runTimeout runs the command given by args, killing it if it's still running
+// after limit, with killAfter as its KillTimeout. A limit of zero runs it
+// without one. The status is 124 if the command timed out.
+func (r *Runner) runTimeout(limit, killAfter time.Duration, args []string, st stdio) int {
+	parent := r.ctx
+	ctx, cancel := context.WithCancel(parent)
+	if limit > 0 {
+		ctx, cancel = context.WithTimeout(parent, limit)
+	}
+	defer cancel()
+	savedTimeout := r.KillTimeout
+	r.ctx, r.KillTimeout = ctx, killAfter
+	defer func() { r.ctx, r.KillTimeout = parent, savedTimeout }()
+
+	cmd := r.newShellCmd(args)
+	if cmd == nil {
+		fmt.Fprintf(st.err, "timeout: %s: command not found\n", args[0])
+		return 127
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = st.in, st.out, st.err
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(st.err, "timeout: %s: %v\n", args[0], err)
+		return 126
+	}
+	err := cmd.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		return 124
+	}
+	return exitStatus(err)
+}
+
+// parseDuration parses a timeout duration: a non-negative decimal number of
+// seconds, or of minutes, hours or days with an m, h or d suffix.
+func parseDuration(s string) (time.Duration, bool) {
+	unit := time.Second
+	switch {
+	case strings.HasSuffix(s, "s"):
+		s = s[:len(s)-1]
+	case strings.HasSuffix(s, "m"):
+		unit, s = time.Minute, s[:len(s)-1]
+	case strings.HasSuffix(s, "h"):
+		unit, s = time.Hour, s[:len(s)-1]
+	case strings.HasSuffix(s, "d"):
+		unit, s = 24*time.Hour, s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return time.Duration(n * float64(unit)), true
+}
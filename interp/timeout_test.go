@@ -0,0 +1,53 @@
+package interp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuiltinTimeout(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"timeout 5 true; echo $?", "0\n"},
+		{"timeout 5 false; echo $?", "1\n"},
+		{"timeout 0.1 sleep 5; echo $?", "124\n"},
+		{"timeout -k 0 0.1 sh -c 'trap \"\" TERM; sleep 5'; echo $?", "124\n"},
+		{"f() { for ((;;)); do x=1; done; }; timeout 0.1 f; echo $?", "124\n"},
+		{"timeout 1x true 2>/dev/null; echo $?", "125\n"},
+		{"timeout 5 gsh_test_missing 2>/dev/null; echo $?", "127\n"},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.src, got, tt.want)
+		}
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("%q took %v", tt.src, elapsed)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"2", 2 * time.Second, true},
+		{"0.5s", 500 * time.Millisecond, true},
+		{"1.5m", 90 * time.Second, true},
+		{"1h", time.Hour, true},
+		{"1d", 24 * time.Hour, true},
+		{"-1", 0, false},
+		{"nan", 0, false},
+		{"1x", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseDuration(tt.s)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseDuration(%q) = %v, %v, want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
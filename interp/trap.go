@@ -0,0 +1,206 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// pseudoSignals are the trap conditions that don't correspond to a real signal,
+// in the order trap -p lists them after the real signals.
+var pseudoSignals = []string{"DEBUG", "ERR", "RETURN"}
+
+// ignoredOnEntry records the signals that were already ignored when the shell
+// started. POSIX doesn't allow a non-interactive shell to trap or reset these.
+var ignoredOnEntry = make(map[syscall.Signal]bool)
+
+func init() {
+	for _, s := range signalNames {
+		if signal.Ignored(s.sig) {
+			ignoredOnEntry[s.sig] = true
+		}
+	}
+	defaultBuiltins["trap"] = BuiltinFunc(func(c *Call) int {
+		r, args := c.Runner, c.Args
+		args = args[1:]
+		list := false
+	options:
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+			switch args[0] {
+			case "--":
+				args = args[1:]
+				break options
+			case "-p":
+				list = true
+			case "-l":
+				printSignalList(c.Stdout)
+				return 0
+			default:
+				fmt.Fprintf(c.Stderr, "trap: %s: invalid option\n", args[0])
+				return 1
+			}
+			args = args[1:]
+		}
+		if list || len(args) == 0 {
+			return r.printTraps(args, c.Stdout, c.Stderr)
+		}
+
+		// A lone condition, or a first operand that is an unsigned integer,
+		// resets every operand to its default disposition.
+		action, conds := args[0], args[1:]
+		if _, err := strconv.ParseUint(action, 10, 32); err == nil || len(args) == 1 {
+			action, conds = "-", args
+		}
+		status := 0
+		for _, spec := range conds {
+			cond, sig, ok := parseTrapCondition(spec)
+			if !ok {
+				fmt.Fprintf(c.Stderr, "trap: %s: invalid signal specification\n", spec)
+				status = 1
+				continue
+			}
+			r.setTrap(cond, sig, action)
+		}
+		return status
+	})
+}
+
+// parseTrapCondition resolves a trap condition given as a signal name, signal
+// number or pseudo-signal. It returns the key used in traps and, for real
+// signals, the signal itself.
+func parseTrapCondition(spec string) (string, syscall.Signal, bool) {
+	upper := strings.ToUpper(spec)
+	if upper == "EXIT" || upper == "0" {
+		return "EXIT", 0, true
+	}
+	for _, name := range pseudoSignals {
+		if upper == name {
+			return name, 0, true
+		}
+	}
+	sig, ok := lookupSignal(spec)
+	if !ok {
+		return "", 0, false
+	}
+	return signalName(sig), sig, true
+}
+
+// setTrap installs action for cond. "-" restores the default disposition and
+// an empty action ignores the signal. Ignored signals stay ignored in commands
+// started by newShellCmd, while trapped signals are reset to their default in
+// them, as POSIX requires.
+func (r *Runner) setTrap(cond string, sig syscall.Signal, action string) {
+	if sig != 0 && !r.Interactive && ignoredOnEntry[sig] {
+		return
+	}
+	switch action {
+	case "-":
+		delete(r.traps, cond)
+		if sig != 0 {
+			// Reset alone would leave a signal ignored by an earlier
+			// trap '' ignored. Notify first reinstalls the runtime's
+			// handler, which Reset keeps in place of the default.
+			signal.Notify(r.signals, sig)
+			signal.Reset(sig)
+			if terminates(sig) {
+				setDefault(sig)
+			}
+		}
+	case "":
+		r.traps[cond] = ""
+		if sig != 0 {
+			signal.Ignore(sig)
+		}
+	default:
+		r.traps[cond] = action
+		if sig != 0 {
+			signal.Notify(r.signals, sig)
+		}
+	}
+}
+
+// printTraps writes the traps for the given conditions, or all traps if none
+// are given, in a form that can be read back as input to the shell.
+func (r *Runner) printTraps(conds []string, stdout, stderr io.Writer) int {
+	if len(conds) == 0 {
+		conds = append(conds, "EXIT")
+		for _, s := range signalNames {
+			conds = append(conds, s.name)
+		}
+		conds = append(conds, pseudoSignals...)
+	}
+	status := 0
+	for _, spec := range conds {
+		cond, sig, ok := parseTrapCondition(spec)
+		if !ok {
+			fmt.Fprintf(stderr, "trap: %s: invalid signal specification\n", spec)
+			status = 1
+			continue
+		}
+		action, ok := r.traps[cond]
+		if !ok {
+			continue
+		}
+		if sig != 0 {
+			cond = "SIG" + cond
+		}
+		fmt.Fprintf(stdout, "trap -- %s %s\n", shellQuote(action), cond)
+	}
+	return status
+}
+
+// printSignalList writes the signal numbers and names in the same layout as
+// kill -l, five to a row.
+func printSignalList(stdout io.Writer) {
+	sigs := make([]int, 0, len(signalNames))
+	for _, s := range signalNames {
+		sigs = append(sigs, int(s.sig))
+	}
+	sort.Ints(sigs)
+	for i, n := range sigs {
+		sep := "\t"
+		if (i+1)%5 == 0 || i == len(sigs)-1 {
+			sep = "\n"
+		}
+		fmt.Fprintf(stdout, "%2d) SIG%s%s", n, signalName(syscall.Signal(n)), sep)
+	}
+}
+
+// shellQuote wraps s in single quotes so it reads back as a single word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runTrap runs the action registered for cond, if any. The exit status of the
+// interrupted command is preserved across the trap action.
+func (r *Runner) runTrap(cond string) {
+	action := r.traps[cond]
+	if action == "" || r.inTrap {
+		return
+	}
+	r.inTrap = true
+	saved := r.lastStatus
+	r.runCommandLine(action)
+	r.lastStatus = saved
+	r.inTrap = false
+}
+
+// runPendingTraps runs the actions for signals received since it was last
+// called. Signals received while a trap runs wait for it to finish.
+func (r *Runner) runPendingTraps() {
+	if r.inTrap {
+		return
+	}
+	for {
+		select {
+		case sig := <-r.signals:
+			r.runTrap(signalName(sig.(syscall.Signal)))
+		default:
+			return
+		}
+	}
+}
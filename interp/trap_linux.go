@@ -0,0 +1,18 @@
+package interp
+
+import (
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// setDefault gives sig, a signal whose default action ends the process, that
+// default action. The runtime can't be relied on for this: once Notify has
+// replaced an ignored HUP or INT, Reset ignores it again. Ignore stops the
+// runtime handling sig, so that a later Notify installs its handler again.
+func setDefault(sig syscall.Signal) {
+	signal.Ignore(sig)
+	const sigSetSize = 8 // the kernel's sigset_t
+	var act [4]uint64    // a zeroed struct sigaction, whose handler is SIG_DFL
+	syscall.RawSyscall6(syscall.SYS_RT_SIGACTION, uintptr(sig), uintptr(unsafe.Pointer(&act)), 0, sigSetSize, 0, 0)
+}
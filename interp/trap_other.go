@@ -0,0 +1,9 @@
+//go:build !linux
+
+package interp
+
+import "syscall"
+
+// setDefault does nothing: the runtime's handler stands in for the default
+// action outside Linux.
+func setDefault(sig syscall.Signal) {}
@@ -0,0 +1,46 @@
+package interp
+
+import (
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/chzyer/readline"
+)
+
+// foregroundTerminal returns in if it is a terminal whose foreground process
+// group is the shell's, which the shell can then hand to the commands it
+// runs, or nil.
+func foregroundTerminal(in io.Reader) *os.File {
+	f, ok := in.(*os.File)
+	if !ok || !readline.IsTerminal(int(f.Fd())) {
+		return nil
+	}
+	var pgid int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&pgid)))
+	if errno != 0 || int(pgid) != syscall.Getpgrp() {
+		return nil
+	}
+	return f
+}
+
+// setForeground makes pgid the foreground process group of the terminal tty.
+// The terminal stops a process outside the foreground group that tries this
+// with SIGTTOU unless it blocks or ignores the signal. It is blocked, on this
+// thread only, since commands would inherit it being ignored.
+func setForeground(tty *os.File, pgid int) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	const sigSetSize = 8 // the kernel's sigset_t
+	set, old := uint64(1)<<(syscall.SIGTTOU-1), uint64(0)
+	syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, 0 /* SIG_BLOCK */, uintptr(unsafe.Pointer(&set)), uintptr(unsafe.Pointer(&old)), sigSetSize, 0, 0)
+	p := int32(pgid)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&p)))
+	syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, 2 /* SIG_SETMASK */, uintptr(unsafe.Pointer(&old)), 0, sigSetSize, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
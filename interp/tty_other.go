@@ -0,0 +1,14 @@
+//go:build !linux
+
+package interp
+
+import (
+	"io"
+	"os"
+)
+
+// foregroundTerminal returns nil: commands are only given the terminal on
+// Linux.
+func foregroundTerminal(in io.Reader) *os.File { return nil }
+
+func setForeground(tty *os.File, pgid int) error { return nil }
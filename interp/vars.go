@@ -0,0 +1,117 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KayaLuken/golang-shell/syntax"
+)
+
+func init() {
+	defaultBuiltins["export"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		unexport := false
+		operands := c.Args[1:]
+		for len(operands) > 0 && strings.HasPrefix(operands[0], "-") {
+			opt := operands[0]
+			operands = operands[1:]
+			if opt == "--" {
+				break
+			}
+			switch opt {
+			case "-n":
+				unexport = true
+			case "-p":
+			default:
+				fmt.Fprintf(c.Stderr, "export: %s: invalid option\n", opt)
+				fmt.Fprintln(c.Stderr, "export: usage: export [-n] [-p] [name[=value] ...]")
+				return 2
+			}
+		}
+		if len(operands) == 0 {
+			names := make([]string, 0, len(r.env))
+			for name := range r.env {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(c.Stdout, "export %s=%s\n", name, shellQuote(r.env[name]))
+			}
+			return 0
+		}
+		status := 0
+		for _, arg := range operands {
+			name, value, hasValue := strings.Cut(arg, "=")
+			if !syntax.IsName(name) {
+				fmt.Fprintf(c.Stderr, "export: `%s': not a valid identifier\n", arg)
+				status = 1
+				continue
+			}
+			if hasValue {
+				r.setVar(name, value)
+			}
+			if unexport {
+				if value, ok := r.env[name]; ok {
+					delete(r.env, name)
+					r.vars[name] = value
+				}
+			} else if value, ok := r.vars[name]; ok {
+				delete(r.vars, name)
+				r.env[name] = value
+			}
+		}
+		return status
+	})
+	defaultBuiltins["unset"] = BuiltinFunc(func(c *Call) int {
+		r := c.Runner
+		mode := ""
+		operands := c.Args[1:]
+		for len(operands) > 0 && strings.HasPrefix(operands[0], "-") {
+			opt := operands[0]
+			operands = operands[1:]
+			if opt == "--" {
+				break
+			}
+			if opt != "-f" && opt != "-v" {
+				fmt.Fprintf(c.Stderr, "unset: %s: invalid option\n", opt)
+				fmt.Fprintln(c.Stderr, "unset: usage: unset [-f] [-v] [name ...]")
+				return 2
+			}
+			mode = opt
+		}
+		status := 0
+		for _, name := range operands {
+			if !syntax.IsName(name) {
+				fmt.Fprintf(c.Stderr, "unset: `%s': not a valid identifier\n", name)
+				status = 1
+				continue
+			}
+			switch {
+			case mode == "-f":
+				delete(r.functions, name)
+			case mode == "" && !r.isVar(name):
+				// Without -v, a name that isn't a variable may be a function
+				delete(r.functions, name)
+			default:
+				r.unsetVar(name)
+			}
+		}
+		return status
+	})
+}
+
+// unsetVar removes a variable or array.
+func (r *Runner) unsetVar(name string) {
+	delete(r.vars, name)
+	delete(r.env, name)
+	delete(r.arrays, name)
+}
+
+// isVar reports whether name is set as a variable or array.
+func (r *Runner) isVar(name string) bool {
+	_, inVars := r.vars[name]
+	_, inEnv := r.env[name]
+	_, inArrays := r.arrays[name]
+	return inVars || inEnv || inArrays
+}
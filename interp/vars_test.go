@@ -0,0 +1,27 @@
+package interp
+
+import "testing"
+
+func TestExportUnset(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`x=1; sh -c 'echo ${x-unset}'; export x; sh -c 'echo $x'`, "unset\n1\n"},
+		{`export y=2; y=3; sh -c 'echo $y'`, "3\n"},
+		{`export z=4; export -n z; sh -c 'echo ${z-unset}'; echo $z`, "unset\n4\n"},
+		{`export q='a b'; export | grep '^export q='`, "export q='a b'\n"},
+		{`export 1x=1 2>/dev/null; echo $?`, "1\n"},
+		{`xu=1; unset xu; declare | grep -c '^xu='`, "0\n"},
+		{`au=(x y); unset au; echo ${#au[@]}; compgen -v au`, "0\n"},
+		{`export e=1; unset e; sh -c 'echo ${e-unset}'`, "unset\n"},
+		{`f() { echo f; }; unset f; f 2>/dev/null || echo gone`, "gone\n"},
+		{`f() { echo f; }; f=1; unset f; f; unset -f f; f 2>/dev/null || echo gone`, "f\ngone\n"},
+		{`unset -v nothing; echo $?`, "0\n"},
+	}
+	for _, tt := range tests {
+		if got := runCapture(t, tt.src); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
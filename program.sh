@@ -8,7 +8,7 @@ set -e # Exit early if any commands fail
 # - Edit .shell/compile.sh to change how your program compiles remotely
 (
   cd "$(dirname "$0")" # Ensure compile steps are run within the repository directory
-  go build -o /tmp/build-shell-go app/*.go
+  go build -o /tmp/build-shell-go ./cmd/gsh
 )
 
 
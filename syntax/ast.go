@@ -0,0 +1,132 @@
+package syntax
+
+// Command is a node that can appear as a stage of a pipeline.
+type Command interface {
+	Redirects() []Redirect
+}
+
+// List is a sequence of and-or lists separated by ;, & or newlines.
+type List struct {
+	Items []ListItem
+}
+
+type ListItem struct {
+	AndOr      *AndOr
+	Background bool
+}
+
+// AndOr is a chain of pipelines joined by && and ||. Ops[i] joins
+// Pipelines[i] and Pipelines[i+1].
+type AndOr struct {
+	Pipelines []*Pipeline
+	Ops       []string
+	Text      string // the source text, used to describe background jobs
+}
+
+type Pipeline struct {
+	Negate bool
+	Cmds   []Command
+}
+
+// Redirect is a single redirection such as 2>>log. The target is expanded
+// when the redirection is applied.
+type Redirect struct {
+	Fd     int
+	Op     string
+	Target string
+}
+
+type SimpleCommand struct {
+	Assigns []string // NAME=value words preceding the command name
+	Words   []string
+	Redirs  []Redirect
+}
+
+func (c *SimpleCommand) Redirects() []Redirect { return c.Redirs }
+
+type ElifClause struct {
+	Cond, Body *List
+}
+
+type IfClause struct {
+	Cond, Body *List
+	Elifs      []ElifClause
+	Else       *List // nil if there is no else branch
+	Redirs     []Redirect
+}
+
+func (c *IfClause) Redirects() []Redirect { return c.Redirs }
+
+// ForClause is for name [in words]; do body; done. Without "in" it loops over
+// the positional parameters.
+type ForClause struct {
+	Name   string
+	Words  []string
+	HasIn  bool
+	Body   *List
+	Redirs []Redirect
+}
+
+func (c *ForClause) Redirects() []Redirect { return c.Redirs }
+
+// ArithForClause is for ((init; cond; post)); do body; done.
+type ArithForClause struct {
+	Init, Cond, Post string
+	Body             *List
+	Redirs           []Redirect
+}
+
+func (c *ArithForClause) Redirects() []Redirect { return c.Redirs }
+
+// WhileClause is a while loop, or an until loop if Until is set.
+type WhileClause struct {
+	Until      bool
+	Cond, Body *List
+	Redirs     []Redirect
+}
+
+func (c *WhileClause) Redirects() []Redirect { return c.Redirs }
+
+// CaseItem is one pat1|pat2) body clause of a case command. Term is the
+// operator that ended it: ";;", ";&" to fall through into the next body, or
+// ";;&" to go on testing the next patterns. The last item may have none.
+type CaseItem struct {
+	Patterns []string
+	Body     *List
+	Term     string
+}
+
+type CaseClause struct {
+	Word   string
+	Items  []CaseItem
+	Redirs []Redirect
+}
+
+func (c *CaseClause) Redirects() []Redirect { return c.Redirs }
+
+// BraceGroup is { list; }, which runs list in the current shell.
+type BraceGroup struct {
+	Body   *List
+	Redirs []Redirect
+}
+
+func (c *BraceGroup) Redirects() []Redirect { return c.Redirs }
+
+// Subshell is ( list ), which runs list without affecting the shell's state.
+type Subshell struct {
+	Body   *List
+	Redirs []Redirect
+}
+
+func (c *Subshell) Redirects() []Redirect { return c.Redirs }
+
+// FuncDef is name() body. Running it defines the function; redirections
+// after the body belong to the body and apply each time the function is
+// called.
+type FuncDef struct {
+	Name string
+	Body Command
+	Text string // the source text of Body, for type and declare -f
+}
+
+func (c *FuncDef) Redirects() []Redirect { return nil }
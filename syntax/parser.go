@@ -0,0 +1,939 @@
+// Package syntax parses shell source into a tree of commands. Words are kept
+// as written, quotes included, and are only expanded when the commands run.
+package syntax
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrIncomplete is returned by Parse when the input ends in the middle of a
+// command, such as inside quotes, after a pipe or before a closing fi. The
+// REPL reads another line with the PS2 prompt when it sees it.
+var ErrIncomplete = errors.New("unexpected end of file")
+
+// SyntaxError reports a token that can't appear where it was found.
+type SyntaxError struct {
+	Token string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error near unexpected token `%s'", e.Token)
+}
+
+type tokenKind int
+
+const (
+	tokWord    tokenKind = iota // a word, kept with its quotes until it is expanded
+	tokOp                       // a control operator: ; & && || | ( ) ;; ;& ;;&
+	tokRedir                    // a redirection operator, with an optional fd number
+	tokNewline                  // an unquoted newline
+	tokArith                    // the (( ... )) header of an arithmetic for loop
+	tokEOF
+)
+
+type token struct {
+	kind     tokenKind
+	val      string
+	fd       int // for tokRedir: the explicit fd before the operator, or -1
+	pos, end int // byte offsets of the token in the source
+
+	// alias is the alias expansion the token came from, if any; aliasNext
+	// marks a word following an alias whose value ends in a blank, which is
+	// checked for an alias too.
+	alias     *aliasExpansion
+	aliasNext bool
+}
+
+// aliasExpansion records the aliases being expanded, innermost first, so that
+// an alias isn't expanded again within its own value.
+type aliasExpansion struct {
+	name   string
+	parent *aliasExpansion
+}
+
+func (e *aliasExpansion) expanding(name string) bool {
+	for ; e != nil; e = e.parent {
+		if e.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// redirOps lists the redirection operators, longest first so they are matched
+// greedily.
+var redirOps = []string{">>", ">&", "<&", ">|", ">", "<"}
+
+// controlOps lists the control operators, longest first.
+var controlOps = []string{";;&", ";;", ";&", "&&", "||", ";", "&", "|", "(", ")"}
+
+// lex splits src into tokens. Words keep their quotes and backslashes so they
+// can be expanded when the command runs rather than when it is parsed.
+func lex(src string) ([]token, error) {
+	var toks []token
+	i := 0
+	for i < len(src) {
+		ch := src[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+			continue
+		case ch == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2
+			continue
+		case ch == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case ch == '\n':
+			toks = append(toks, token{kind: tokNewline, val: "newline", pos: i, end: i + 1})
+			i++
+			continue
+		}
+		if op := matchPrefix(src[i:], redirOps); op != "" {
+			toks = append(toks, token{kind: tokRedir, val: op, fd: -1, pos: i, end: i + len(op)})
+			i += len(op)
+			continue
+		}
+		if strings.HasPrefix(src[i:], "((") && len(toks) > 0 && isWord(toks[len(toks)-1], "for") {
+			end, err := scanArith(src, i+2)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, token{kind: tokArith, val: src[i+2 : end-2], pos: i, end: end})
+			i = end
+			continue
+		}
+		if op := matchPrefix(src[i:], controlOps); op != "" {
+			toks = append(toks, token{kind: tokOp, val: op, pos: i, end: i + len(op)})
+			i += len(op)
+			continue
+		}
+
+		start := i
+		end, err := scanWord(src, i)
+		if err != nil {
+			return nil, err
+		}
+		i = end
+		word := src[start:end]
+		// A word of digits directly followed by a redirection is its fd
+		if op := matchPrefix(src[i:], redirOps); op != "" && isAllDigits(word) {
+			toks = append(toks, token{kind: tokRedir, val: op, fd: atoi(word), pos: start, end: i + len(op)})
+			i += len(op)
+			continue
+		}
+		toks = append(toks, token{kind: tokWord, val: word, pos: start, end: end})
+	}
+	toks = append(toks, token{kind: tokEOF, val: "EOF", pos: len(src), end: len(src)})
+	return toks, nil
+}
+
+// scanWord returns the offset just past the word starting at src[i], skipping
+// over quoted sections, escaped characters, command substitutions and the
+// parenthesised list of an array assignment.
+func scanWord(src string, i int) (int, error) {
+	start := i
+	for i < len(src) {
+		switch ch := src[i]; ch {
+		case '(':
+			// name=(words) assigns an array
+			if !IsAssignment(src[start:i]) || !strings.HasSuffix(src[start:i], "=") {
+				return i, nil
+			}
+			end, err := scanParens(src, i+1)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case ' ', '\t', '\n', ';', '&', '|', ')', '<', '>':
+			return i, nil
+		case '\\':
+			if i+1 >= len(src) {
+				return i + 1, nil
+			}
+			i += 2
+		case '\'':
+			end := strings.IndexByte(src[i+1:], '\'')
+			if end < 0 {
+				return 0, ErrIncomplete
+			}
+			i += end + 2
+		case '"':
+			i++
+			for i < len(src) && src[i] != '"' {
+				switch {
+				case src[i] == '\\':
+					i += 2
+				case strings.HasPrefix(src[i:], "$("), src[i] == '`':
+					end, err := scanSubst(src, i)
+					if err != nil {
+						return 0, err
+					}
+					i = end
+				default:
+					i++
+				}
+			}
+			if i >= len(src) {
+				return 0, ErrIncomplete
+			}
+			i++
+		case '`':
+			end, err := scanSubst(src, i)
+			if err != nil {
+				return 0, err
+			}
+			i = end
+		case '$':
+			switch {
+			case i+1 < len(src) && src[i+1] == '{':
+				end := strings.IndexByte(src[i:], '}')
+				if end < 0 {
+					return 0, ErrIncomplete
+				}
+				i += end + 1
+			case i+1 < len(src) && src[i+1] == '(':
+				end, err := scanSubst(src, i)
+				if err != nil {
+					return 0, err
+				}
+				i = end
+			default:
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	return i, nil
+}
+
+// scanSubst returns the offset just past the $(command), $((expression)) or
+// `command` substitution starting at src[i].
+func scanSubst(src string, i int) (int, error) {
+	if src[i] == '`' {
+		for j := i + 1; j < len(src); j++ {
+			switch src[j] {
+			case '\\':
+				j++
+			case '`':
+				return j + 1, nil
+			}
+		}
+		return 0, ErrIncomplete
+	}
+	return scanParens(src, i+2)
+}
+
+// scanParens returns the offset just past the ")" closing a parenthesis
+// opened before src[i], skipping over nested parentheses and quoted or
+// escaped ones.
+func scanParens(src string, i int) (int, error) {
+	depth := 1
+	for i < len(src) {
+		switch src[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i + 1, nil
+			}
+		case '\\':
+			i++
+		case '\'', '"':
+			end, err := scanWord(src, i)
+			if err != nil {
+				return 0, err
+			}
+			// scanWord stops at the end of the quoted section's word
+			i = end
+			continue
+		case '#':
+			// A comment, at the start of a word, runs to the end of the
+			// line
+			if i == 0 || strings.IndexByte(" \t\n(", src[i-1]) >= 0 {
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+				continue
+			}
+		}
+		i++
+	}
+	return 0, ErrIncomplete
+}
+
+// scanArith returns the offset just past the "))" closing the arithmetic
+// expression starting at src[i], allowing for nested parentheses.
+func scanArith(src string, i int) (int, error) {
+	depth := 0
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				if i+1 < len(src) && src[i+1] == ')' {
+					return i + 2, nil
+				}
+				return 0, &SyntaxError{")"}
+			}
+			depth--
+		}
+	}
+	return 0, ErrIncomplete
+}
+
+func matchPrefix(s string, ops []string) string {
+	for _, op := range ops {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isAllDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func atoi(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		n = n*10 + int(s[i]-'0')
+	}
+	return n
+}
+
+// reservedWords can't start a simple command; they are only recognised as the
+// first word of a command.
+var reservedWords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"for": true, "while": true, "until": true, "do": true, "done": true,
+	"case": true, "esac": true, "function": true, "{": true, "}": true,
+}
+
+// Parser parses shell source. The zero Parser is ready to use and expands no
+// aliases.
+type Parser struct {
+	// Aliases, if set, returns the value of the alias name. The first word
+	// of a simple command is replaced by its alias, if it has one, when it
+	// is parsed.
+	Aliases func(name string) (value string, ok bool)
+}
+
+type parser struct {
+	src     string
+	toks    []token
+	pos     int
+	aliases func(name string) (string, bool)
+}
+
+// Parse parses src into a command list without expanding aliases. It returns
+// ErrIncomplete if src ends in the middle of a command and a *SyntaxError if
+// it is malformed.
+func Parse(src string) (*List, error) {
+	return (&Parser{}).Parse(src)
+}
+
+// Parse parses src into a command list like the Parse function, expanding
+// aliases.
+func (pr *Parser) Parse(src string) (*List, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, toks: toks, aliases: pr.Aliases}
+	l, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{t.val}
+	}
+	return l, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) skipNewlines() {
+	for p.peek().kind == tokNewline {
+		p.pos++
+	}
+}
+
+// expandAlias replaces the word about to be parsed with the tokens of its
+// alias, repeatedly as long as the first of them is an alias too. It reports
+// whether the word was replaced. Each token of the value spans the aliased
+// word in the source, so command texts show the alias as it was typed.
+func (p *parser) expandAlias() bool {
+	expanded := false
+	for {
+		t := p.peek()
+		if p.aliases == nil || t.kind != tokWord || t.alias.expanding(t.val) {
+			return expanded
+		}
+		value, ok := p.aliases(t.val)
+		if !ok {
+			return expanded
+		}
+		toks, err := lex(value)
+		if err != nil {
+			return expanded
+		}
+		toks = toks[:len(toks)-1] // drop the EOF
+		exp := &aliasExpansion{name: t.val, parent: t.alias}
+		for i := range toks {
+			toks[i].pos, toks[i].end, toks[i].alias = t.pos, t.end, exp
+		}
+		p.toks = slices.Concat(p.toks[:p.pos], toks, p.toks[p.pos+1:])
+		expanded = true
+		if strings.HasSuffix(value, " ") || strings.HasSuffix(value, "\t") {
+			p.toks[p.pos+len(toks)].aliasNext = true
+		}
+		if len(toks) == 0 {
+			return expanded
+		}
+	}
+}
+
+// isWord reports whether t is the unquoted word w, as used to recognise
+// reserved words.
+func isWord(t token, w string) bool {
+	return t.kind == tokWord && t.val == w
+}
+
+// atStop reports whether t is one of the words or operators that end the list
+// being parsed.
+func atStop(t token, stops []string) bool {
+	if t.kind != tokWord && t.kind != tokOp {
+		return false
+	}
+	for _, s := range stops {
+		if t.val == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList parses and-or lists until EOF or, at the start of a command, one
+// of the stop words or operators.
+func (p *parser) parseList(stops ...string) (*List, error) {
+	l := &List{}
+	for {
+		p.skipNewlines()
+		t := p.peek()
+		if t.kind == tokEOF || atStop(t, stops) {
+			return l, nil
+		}
+		ao, err := p.parseAndOr()
+		if err != nil {
+			return nil, err
+		}
+		item := ListItem{AndOr: ao}
+		switch t := p.peek(); {
+		case t.kind == tokOp && t.val == ";":
+			p.next()
+		case t.kind == tokOp && t.val == "&":
+			p.next()
+			item.Background = true
+		case t.kind == tokNewline, t.kind == tokEOF, atStop(t, stops):
+		default:
+			return nil, &SyntaxError{t.val}
+		}
+		l.Items = append(l.Items, item)
+	}
+}
+
+// parseBody parses a non-empty list ending at one of stops, as used for the
+// parts of compound commands.
+func (p *parser) parseBody(stops ...string) (*List, error) {
+	l, err := p.parseList(stops...)
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind == tokEOF {
+		return nil, ErrIncomplete
+	}
+	if len(l.Items) == 0 {
+		return nil, &SyntaxError{t.val}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAndOr() (*AndOr, error) {
+	start := p.peek().pos
+	pl, err := p.parsePipeline()
+	if err != nil {
+		return nil, err
+	}
+	ao := &AndOr{Pipelines: []*Pipeline{pl}}
+	for t := p.peek(); t.kind == tokOp && (t.val == "&&" || t.val == "||"); t = p.peek() {
+		p.next()
+		p.skipNewlines()
+		pl, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		ao.Ops = append(ao.Ops, t.val)
+		ao.Pipelines = append(ao.Pipelines, pl)
+	}
+	ao.Text = strings.TrimSpace(p.src[start:p.toks[p.pos-1].end])
+	return ao, nil
+}
+
+func (p *parser) parsePipeline() (*Pipeline, error) {
+	pl := &Pipeline{}
+	if isWord(p.peek(), "!") {
+		p.next()
+		pl.Negate = true
+	}
+	for {
+		c, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pl.Cmds = append(pl.Cmds, c)
+		if t := p.peek(); t.kind != tokOp || t.val != "|" {
+			return pl, nil
+		}
+		p.next()
+		p.skipNewlines()
+	}
+}
+
+func (p *parser) parseCommand() (Command, error) {
+	// A word followed by ( names a function being defined, not a command
+	if next := p.toks[min(p.pos+1, len(p.toks)-1)]; next.kind != tokOp || next.val != "(" {
+		p.expandAlias()
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokEOF:
+		return nil, ErrIncomplete
+	case isWord(t, "if"):
+		return p.parseIf()
+	case isWord(t, "for"):
+		return p.parseFor()
+	case isWord(t, "while"), isWord(t, "until"):
+		return p.parseWhile()
+	case isWord(t, "case"):
+		return p.parseCase()
+	case isWord(t, "{"):
+		return p.parseBraceGroup()
+	case t.kind == tokOp && t.val == "(":
+		return p.parseSubshell()
+	case isWord(t, "function"):
+		p.next()
+		return p.parseFunction(true)
+	case t.kind == tokWord && IsName(t.val) && p.toks[p.pos+1].kind == tokOp && p.toks[p.pos+1].val == "(":
+		return p.parseFunction(false)
+	case t.kind == tokWord && reservedWords[t.val]:
+		return nil, &SyntaxError{t.val}
+	}
+	return p.parseSimple()
+}
+
+func (p *parser) parseSimple() (Command, error) {
+	c := &SimpleCommand{}
+	for {
+		t := p.peek()
+		switch t.kind {
+		case tokWord:
+			// The command name after assignments is checked for an alias,
+			// as is a word after an alias ending in a blank
+			if (len(c.Assigns) > 0 && len(c.Words) == 0 && !IsAssignment(t.val) || t.aliasNext) && p.expandAlias() {
+				continue
+			}
+			p.next()
+			if len(c.Words) == 0 && IsAssignment(t.val) {
+				c.Assigns = append(c.Assigns, t.val)
+			} else {
+				c.Words = append(c.Words, t.val)
+			}
+			continue
+		case tokRedir:
+			r, err := p.parseRedirect()
+			if err != nil {
+				return nil, err
+			}
+			c.Redirs = append(c.Redirs, r)
+			continue
+		}
+		if len(c.Assigns) == 0 && len(c.Words) == 0 && len(c.Redirs) == 0 {
+			return nil, &SyntaxError{t.val}
+		}
+		return c, nil
+	}
+}
+
+func (p *parser) parseRedirect() (Redirect, error) {
+	t := p.next()
+	r := Redirect{Fd: t.fd, Op: t.val}
+	if r.Fd < 0 {
+		r.Fd = 1
+		if t.val == "<" || t.val == "<&" {
+			r.Fd = 0
+		}
+	}
+	target := p.next()
+	if target.kind != tokWord {
+		return r, &SyntaxError{target.val}
+	}
+	r.Target = target.val
+	return r, nil
+}
+
+// parseRedirects parses the redirections following a compound command.
+func (p *parser) parseRedirects() ([]Redirect, error) {
+	var redirs []Redirect
+	for p.peek().kind == tokRedir {
+		r, err := p.parseRedirect()
+		if err != nil {
+			return nil, err
+		}
+		redirs = append(redirs, r)
+	}
+	return redirs, nil
+}
+
+// expect consumes the reserved word w.
+func (p *parser) expect(w string) error {
+	t := p.next()
+	if t.kind == tokEOF {
+		return ErrIncomplete
+	}
+	if !isWord(t, w) {
+		return &SyntaxError{t.val}
+	}
+	return nil
+}
+
+func (p *parser) parseIf() (Command, error) {
+	p.next()
+	c := &IfClause{}
+	var err error
+	if c.Cond, err = p.parseBody("then"); err != nil {
+		return nil, err
+	}
+	p.next()
+	if c.Body, err = p.parseBody("elif", "else", "fi"); err != nil {
+		return nil, err
+	}
+	for isWord(p.peek(), "elif") {
+		p.next()
+		var elif ElifClause
+		if elif.Cond, err = p.parseBody("then"); err != nil {
+			return nil, err
+		}
+		p.next()
+		if elif.Body, err = p.parseBody("elif", "else", "fi"); err != nil {
+			return nil, err
+		}
+		c.Elifs = append(c.Elifs, elif)
+	}
+	if isWord(p.peek(), "else") {
+		p.next()
+		if c.Else, err = p.parseBody("fi"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("fi"); err != nil {
+		return nil, err
+	}
+	c.Redirs, err = p.parseRedirects()
+	return c, err
+}
+
+// parseDoGroup parses do list done.
+func (p *parser) parseDoGroup() (*List, error) {
+	if err := p.expect("do"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseBody("done")
+	if err != nil {
+		return nil, err
+	}
+	p.next()
+	return body, nil
+}
+
+func (p *parser) parseFor() (Command, error) {
+	p.next()
+	t := p.next()
+	switch {
+	case t.kind == tokEOF:
+		return nil, ErrIncomplete
+	case t.kind == tokArith:
+		return p.parseArithFor(t.val)
+	case t.kind != tokWord || !IsName(t.val):
+		return nil, &SyntaxError{t.val}
+	}
+	c := &ForClause{Name: t.val}
+	p.skipNewlines()
+	if isWord(p.peek(), "in") {
+		p.next()
+		c.HasIn = true
+		for p.peek().kind == tokWord {
+			c.Words = append(c.Words, p.next().val)
+		}
+		switch t := p.next(); {
+		case t.kind == tokEOF:
+			return nil, ErrIncomplete
+		case t.kind != tokNewline && !(t.kind == tokOp && t.val == ";"):
+			return nil, &SyntaxError{t.val}
+		}
+	} else if t := p.peek(); t.kind == tokOp && t.val == ";" {
+		p.next()
+	}
+	p.skipNewlines()
+	var err error
+	if c.Body, err = p.parseDoGroup(); err != nil {
+		return nil, err
+	}
+	c.Redirs, err = p.parseRedirects()
+	return c, err
+}
+
+func (p *parser) parseArithFor(header string) (Command, error) {
+	parts := strings.Split(header, ";")
+	if len(parts) != 3 {
+		return nil, &SyntaxError{"(("}
+	}
+	c := &ArithForClause{
+		Init: strings.TrimSpace(parts[0]),
+		Cond: strings.TrimSpace(parts[1]),
+		Post: strings.TrimSpace(parts[2]),
+	}
+	if t := p.peek(); t.kind == tokOp && t.val == ";" {
+		p.next()
+	}
+	p.skipNewlines()
+	var err error
+	if c.Body, err = p.parseDoGroup(); err != nil {
+		return nil, err
+	}
+	c.Redirs, err = p.parseRedirects()
+	return c, err
+}
+
+func (p *parser) parseWhile() (Command, error) {
+	c := &WhileClause{Until: p.next().val == "until"}
+	var err error
+	if c.Cond, err = p.parseBody("do"); err != nil {
+		return nil, err
+	}
+	if c.Body, err = p.parseDoGroup(); err != nil {
+		return nil, err
+	}
+	c.Redirs, err = p.parseRedirects()
+	return c, err
+}
+
+func (p *parser) parseCase() (Command, error) {
+	p.next()
+	t := p.next()
+	switch {
+	case t.kind == tokEOF:
+		return nil, ErrIncomplete
+	case t.kind != tokWord:
+		return nil, &SyntaxError{t.val}
+	}
+	c := &CaseClause{Word: t.val}
+	p.skipNewlines()
+	if err := p.expect("in"); err != nil {
+		return nil, err
+	}
+	for {
+		p.skipNewlines()
+		if isWord(p.peek(), "esac") {
+			p.next()
+			break
+		}
+		item, err := p.parseCaseItem()
+		if err != nil {
+			return nil, err
+		}
+		c.Items = append(c.Items, item)
+		if item.Term == "" {
+			p.skipNewlines()
+			if err := p.expect("esac"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	var err error
+	c.Redirs, err = p.parseRedirects()
+	return c, err
+}
+
+// parseCaseItem parses [(]pattern[|pattern]...) [list] [;;|;&|;;&].
+func (p *parser) parseCaseItem() (CaseItem, error) {
+	var item CaseItem
+	if t := p.peek(); t.kind == tokOp && t.val == "(" {
+		p.next()
+	}
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokEOF:
+			return item, ErrIncomplete
+		case t.kind != tokWord:
+			return item, &SyntaxError{t.val}
+		}
+		item.Patterns = append(item.Patterns, t.val)
+
+		t = p.next()
+		if t.kind == tokEOF {
+			return item, ErrIncomplete
+		}
+		if t.kind == tokOp && t.val == ")" {
+			break
+		}
+		if t.kind != tokOp || t.val != "|" {
+			return item, &SyntaxError{t.val}
+		}
+	}
+	var err error
+	if item.Body, err = p.parseList(";;", ";&", ";;&", "esac"); err != nil {
+		return item, err
+	}
+	switch t := p.peek(); {
+	case t.kind == tokEOF:
+		return item, ErrIncomplete
+	case t.kind == tokOp && (t.val == ";;" || t.val == ";&" || t.val == ";;&"):
+		item.Term = p.next().val
+	}
+	return item, nil
+}
+
+// parseFunction parses a function definition from its name on. With the
+// function keyword, the parentheses after the name are optional.
+func (p *parser) parseFunction(keyword bool) (Command, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokEOF:
+		return nil, ErrIncomplete
+	case t.kind != tokWord || !IsName(t.val):
+		return nil, &SyntaxError{t.val}
+	}
+	c := &FuncDef{Name: t.val}
+	if t := p.peek(); !keyword || t.kind == tokOp && t.val == "(" {
+		for _, op := range []string{"(", ")"} {
+			t := p.next()
+			if t.kind == tokEOF {
+				return nil, ErrIncomplete
+			}
+			if t.kind != tokOp || t.val != op {
+				return nil, &SyntaxError{t.val}
+			}
+		}
+	}
+	p.skipNewlines()
+	start := p.peek().pos
+	var err error
+	switch t := p.peek(); {
+	case t.kind == tokEOF:
+		return nil, ErrIncomplete
+	case isWord(t, "{"), t.kind == tokOp && t.val == "(",
+		isWord(t, "if"), isWord(t, "for"), isWord(t, "while"), isWord(t, "until"), isWord(t, "case"):
+		c.Body, err = p.parseCommand()
+	default:
+		return nil, &SyntaxError{t.val}
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Text = p.src[start:p.toks[p.pos-1].end]
+	return c, nil
+}
+
+func (p *parser) parseSubshell() (Command, error) {
+	p.next()
+	c := &Subshell{}
+	var err error
+	if c.Body, err = p.parseBody(")"); err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.kind != tokOp || t.val != ")" {
+		return nil, &SyntaxError{t.val}
+	}
+	c.Redirs, err = p.parseRedirects()
+	return c, err
+}
+
+func (p *parser) parseBraceGroup() (Command, error) {
+	p.next()
+	c := &BraceGroup{}
+	var err error
+	if c.Body, err = p.parseBody("}"); err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	c.Redirs, err = p.parseRedirects()
+	return c, err
+}
+
+// IsAssignment reports whether word has the form NAME=value. The name may
+// be followed by + to append to the variable, or by a [subscript] to assign
+// an element of an array.
+func IsAssignment(word string) bool {
+	eq := strings.IndexByte(word, '=')
+	if eq <= 0 {
+		return false
+	}
+	name := strings.TrimSuffix(word[:eq], "+")
+	if open := strings.IndexByte(name, '['); open > 0 && strings.HasSuffix(name, "]") {
+		name = name[:open]
+	}
+	return IsName(name)
+}
+
+// IsName reports whether s is a valid variable name.
+func IsName(s string) bool {
+	if s == "" || isDigit(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' && !isAlpha(s[i]) && !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
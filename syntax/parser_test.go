@@ -0,0 +1,232 @@
+package syntax
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse_Incomplete(t *testing.T) {
+	inputs := []string{
+		"if true; then echo a\n",
+		"if true\n",
+		"if true; then echo a; else\n",
+		"echo 'unterminated\n",
+		"echo a |\n",
+		"true &&\n",
+	}
+	for _, input := range inputs {
+		if _, err := Parse(input); err != ErrIncomplete {
+			t.Errorf("Parse(%q) error = %v, want ErrIncomplete", input, err)
+		}
+	}
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"fi\n", "syntax error near unexpected token `fi'"},
+		{"if then echo a; fi\n", "syntax error near unexpected token `then'"},
+		{"echo a; ; echo b\n", "syntax error near unexpected token `;'"},
+		{"echo >\n", "syntax error near unexpected token `newline'"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestParse_IfClause(t *testing.T) {
+	l, err := Parse("if a; then b; elif c\nthen d; else e; fi 2>err.log")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	c, ok := l.Items[0].AndOr.Pipelines[0].Cmds[0].(*IfClause)
+	if !ok {
+		t.Fatalf("parsed command is %T, want *IfClause", l.Items[0].AndOr.Pipelines[0].Cmds[0])
+	}
+	if len(c.Elifs) != 1 || c.Else == nil {
+		t.Errorf("if clause has %d elifs and else %v, want 1 elif and an else", len(c.Elifs), c.Else != nil)
+	}
+	want := Redirect{Fd: 2, Op: ">", Target: "err.log"}
+	if len(c.Redirs) != 1 || c.Redirs[0] != want {
+		t.Errorf("if clause redirects = %+v, want [%+v]", c.Redirs, want)
+	}
+}
+
+func TestParse_Loops(t *testing.T) {
+	inputs := []string{
+		"for x in a b; do echo $x; done",
+		"for x\ndo echo $x\ndone",
+		"for ((i=0; i<3; i++)); do echo $i; done",
+		"while true; do break; done > out",
+		"until false\ndo\nbreak\ndone",
+	}
+	for _, input := range inputs {
+		if _, err := Parse(input); err != nil {
+			t.Errorf("Parse(%q) returned error: %v", input, err)
+		}
+	}
+	for _, input := range []string{"for x in a; do", "while true; do echo", "for ((i=0;"} {
+		if _, err := Parse(input); err != ErrIncomplete {
+			t.Errorf("Parse(%q) error = %v, want ErrIncomplete", input, err)
+		}
+	}
+}
+
+func TestParse_Case(t *testing.T) {
+	inputs := []string{
+		"case x in a|b) echo ab;; (c) echo c;& *) ;;& esac",
+		"case x in\n  a)\n    echo a\n    ;;\n  b) echo b\nesac",
+		"case x in esac",
+	}
+	for _, input := range inputs {
+		if _, err := Parse(input); err != nil {
+			t.Errorf("Parse(%q) returned error: %v", input, err)
+		}
+	}
+	for _, input := range []string{"case x in a) echo a;;", "case x", "case x in a|"} {
+		if _, err := Parse(input); err != ErrIncomplete {
+			t.Errorf("Parse(%q) error = %v, want ErrIncomplete", input, err)
+		}
+	}
+	if _, err := Parse("case x in a) echo a;; fi"); err == nil {
+		t.Errorf("Parse should reject a case without esac")
+	}
+}
+
+func TestParse_Functions(t *testing.T) {
+	inputs := []string{
+		"f() { echo a; }",
+		"f ()\n{\n  echo a\n}",
+		"function f { echo a; }",
+		"function f() { echo a; } > out",
+		"f() if true; then echo a; fi",
+	}
+	for _, input := range inputs {
+		l, err := Parse(input)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", input, err)
+			continue
+		}
+		if _, ok := l.Items[0].AndOr.Pipelines[0].Cmds[0].(*FuncDef); !ok {
+			t.Errorf("Parse(%q) = %T, want *FuncDef", input, l.Items[0].AndOr.Pipelines[0].Cmds[0])
+		}
+	}
+	if _, err := Parse("f() {\necho a\n"); err != ErrIncomplete {
+		t.Errorf("Parse of an unterminated body error = %v, want ErrIncomplete", err)
+	}
+	if _, err := Parse("f() echo a"); err == nil {
+		t.Errorf("Parse should reject a simple command as a function body")
+	}
+}
+
+func TestParse_Groups(t *testing.T) {
+	for _, input := range []string{"( cd / && pwd )", "{ echo a; echo b; } > out", "(\necho a\n) | cat", "f() ( echo a )"} {
+		if _, err := Parse(input); err != nil {
+			t.Errorf("Parse(%q) returned error: %v", input, err)
+		}
+	}
+	for _, input := range []string{"( echo a", "{ echo a;", "{ echo a }"} {
+		if _, err := Parse(input); err != ErrIncomplete {
+			t.Errorf("Parse(%q) error = %v, want ErrIncomplete", input, err)
+		}
+	}
+	for _, input := range []string{"( )", "{ }", "(echo a))"} {
+		if _, err := Parse(input); err == nil || err == ErrIncomplete {
+			t.Errorf("Parse(%q) error = %v, want a syntax error", input, err)
+		}
+	}
+}
+
+func TestParse_Words(t *testing.T) {
+	tests := []struct {
+		input   string
+		assigns []string
+		words   []string
+	}{
+		{"echo $(echo a; echo b) x", nil, []string{"echo", "$(echo a; echo b)", "x"}},
+		{"echo \"$(echo ')')\"", nil, []string{"echo", "\"$(echo ')')\""}},
+		{"echo `echo a b`", nil, []string{"echo", "`echo a b`"}},
+		{"echo $((1 + (2)))", nil, []string{"echo", "$((1 + (2)))"}},
+		{"a=(x 'y )' z) b+=(w) c[1]=v", []string{"a=(x 'y )' z)", "b+=(w)", "c[1]=v"}, nil},
+		{"a=(x # )\ny)", []string{"a=(x # )\ny)"}, nil},
+	}
+	for _, tt := range tests {
+		l, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		c := l.Items[0].AndOr.Pipelines[0].Cmds[0].(*SimpleCommand)
+		if !slices.Equal(c.Assigns, tt.assigns) || !slices.Equal(c.Words, tt.words) {
+			t.Errorf("Parse(%q) = %q %q, want %q %q", tt.input, c.Assigns, c.Words, tt.assigns, tt.words)
+		}
+	}
+	for _, input := range []string{"echo $(echo a", "a=(x y", "echo `a"} {
+		if _, err := Parse(input); err != ErrIncomplete {
+			t.Errorf("Parse(%q) error = %v, want ErrIncomplete", input, err)
+		}
+	}
+}
+
+func TestParser_Aliases(t *testing.T) {
+	aliases := map[string]string{
+		"ll":    "ls -l",
+		"ls":    "ls -F",
+		"loop":  "loop2",
+		"loop2": "loop",
+		"sudo":  "sudo ",
+		"grep":  "grep --color",
+		"when":  "if true; then",
+		"pipe":  "echo a | cat",
+	}
+	p := &Parser{Aliases: func(name string) (string, bool) {
+		v, ok := aliases[name]
+		return v, ok
+	}}
+	tests := []struct {
+		src  string
+		want [][]string // the words of each command in the first pipeline
+	}{
+		{"ll /tmp", [][]string{{"ls", "-F", "-l", "/tmp"}}},
+		{"loop x", [][]string{{"loop", "x"}}},
+		{"sudo ll", [][]string{{"sudo", "ls", "-F", "-l"}}},
+		{"echo ll", [][]string{{"echo", "ll"}}},
+		{"'ll' x", [][]string{{"'ll'", "x"}}},
+		{`\ll x`, [][]string{{`\ll`, "x"}}},
+		{"X=1 ll", [][]string{{"ls", "-F", "-l"}}},
+		{"pipe | grep a", [][]string{{"echo", "a"}, {"cat"}, {"grep", "--color", "a"}}},
+	}
+	for _, tt := range tests {
+		l, err := p.Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.src, err)
+			continue
+		}
+		var got [][]string
+		for _, c := range l.Items[0].AndOr.Pipelines[0].Cmds {
+			got = append(got, c.(*SimpleCommand).Words)
+		}
+		if !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("Parse(%q) commands = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+
+	l, err := p.Parse("when echo yes; fi")
+	if err != nil {
+		t.Fatalf("alias of reserved words: %v", err)
+	}
+	if _, ok := l.Items[0].AndOr.Pipelines[0].Cmds[0].(*IfClause); !ok {
+		t.Errorf("alias of reserved words parsed as %T, want *IfClause", l.Items[0].AndOr.Pipelines[0].Cmds[0])
+	}
+	if l.Items[0].AndOr.Text != "when echo yes; fi" {
+		t.Errorf("text = %q, want the source as written", l.Items[0].AndOr.Text)
+	}
+	if l, _ := Parse("ll"); l.Items[0].AndOr.Pipelines[0].Cmds[0].(*SimpleCommand).Words[0] != "ll" {
+		t.Errorf("Parse expanded an alias")
+	}
+}